@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/fs"
+	"github.com/spf13/afero"
+)
+
+// maxSummaryEntries is the number of file tree entries of an existing repository included in prompts
+const maxSummaryEntries = 300
+
+type addFlags struct {
+	into           string
+	config         string
+	profile        string
+	name           string
+	allowOverwrite bool
+}
+
+// newAddModel creates a model that adds a project described by description to the
+// existing repository in f.into
+func newAddModel(f addFlags, description string) (generateCmdModel, error) {
+	req, _, err := loadRequest(f.config, f.profile)
+	if err != nil {
+		return generateCmdModel{}, err
+	}
+	req.ProjectDescription = description
+	if f.name != "" {
+		req.ProjectName = f.name
+	}
+	// The repository already exists, so there is nothing to initialize
+	req.GitRepo = false
+
+	// Load the repository before any LLM calls, so unreadable or unwritable targets fail fast
+	osFs := afero.NewOsFs()
+	existing, err := fs.LoadDir(osFs, f.into)
+	if err != nil {
+		return generateCmdModel{}, err
+	}
+	if err := fs.EnsureWritableDir(osFs, f.into); err != nil {
+		return generateCmdModel{}, err
+	}
+	summary, err := existing.Summarize(maxSummaryEntries)
+	if err != nil {
+		return generateCmdModel{}, err
+	}
+
+	m, err := newModel(req)
+	if err != nil {
+		return generateCmdModel{}, err
+	}
+	m.state = Initializing
+	m.into = f.into
+	m.augment = &core.AugmentOptions{
+		Existing:       existing,
+		Summary:        summary,
+		AllowOverwrite: f.allowOverwrite,
+	}
+	return m, nil
+}
+
+// handleAugmentFinalization writes the generated files into the existing repository,
+// refusing to overwrite existing files unless allowed
+func (m *generateCmdModel) handleAugmentFinalization() (tea.Model, tea.Cmd) {
+	osFs := afero.NewOsFs()
+	conflicts, err := m.engine.fs.Conflicts(osFs, ".", m.into)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to check for existing files: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+	if len(conflicts) > 0 && !m.augment.AllowOverwrite {
+		err := &fs.OutputExistsError{Path: m.into, Conflicts: conflicts}
+		m.logger.Error(fmt.Sprintf("Refusing to overwrite existing files: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s (use --allow-overwrite to replace them)", err), tea.Quit)
+	}
+
+	// The files are staged next to the repository and moved into it, undoing the moves if one
+	// fails, so that a failure doesn't leave a half-applied addition
+	into, err := filepath.Abs(m.into)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to resolve repository path: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+	report, err := m.engine.fs.WriteProject(osFs, ".", into, fs.ExistsMerge)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to write files to repository: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+	report.Path = m.into
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	finalMsg := fmt.Sprintf("Project added to repository: %s", nameStyle.Render(m.into))
+	finalMsg += formatWriteReport(report)
+	finalMsg += formatUsage(m.request.Usage.Usage())
+	return m, tea.Printf("%s", finalMsg)
+}
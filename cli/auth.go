@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/config"
+	"github.com/santiagomed/boil/credentials"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+	"golang.org/x/term"
+)
+
+type authFlags struct {
+	// provider is the provider whose key is stored or removed, defaulting to the configured one
+	provider string
+	config   string
+	// profile is the profile the key is stored for, or the profile of the configuration
+	profile string
+}
+
+// credentialStore opens the store of the user's API keys
+func credentialStore() (credentials.Store, error) {
+	return credentials.Open(afero.NewOsFs(), promptPassphrase)
+}
+
+// promptPassphrase asks for the passphrase of the encrypted credentials file on the terminal
+func promptPassphrase(create bool) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("a passphrase is required for the credentials file (set %s)", credentials.PassphraseEnv)
+	}
+	if create {
+		fmt.Fprintln(os.Stderr, "No OS keyring is available, so API keys are stored in a file encrypted with a passphrase.")
+	}
+	pass, err := readSecret("Passphrase for the credentials file: ")
+	if err != nil || !create {
+		return pass, err
+	}
+	confirm, err := readSecret("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass != confirm {
+		return "", errors.New("the passphrases don't match")
+	}
+	return pass, nil
+}
+
+// readSecret reads a line from the terminal without echoing it
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading input: %w", err)
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// authProvider returns the provider of the flags, or the configured provider
+func authProvider(f authFlags) (string, error) {
+	provider := f.provider
+	if provider == "" {
+		req, _, err := config.Load(config.Options{ConfigPath: f.config, Profile: f.profile})
+		if err != nil {
+			return "", fmt.Errorf("error loading config: %w", err)
+		}
+		provider = req.Provider
+	}
+	if !llm.IsKnownProvider(provider) {
+		return "", fmt.Errorf("unknown provider %q (supported providers: %s)", provider, strings.Join(llm.Providers(), ", "))
+	}
+	return provider, nil
+}
+
+// runAuthLogin stores the API key of a provider, or of a provider for a profile, read from the
+// terminal or from in if it isn't a terminal, and removes the plaintext key from the user
+// configuration file
+func runAuthLogin(f authFlags, in io.Reader, out io.Writer) error {
+	provider, err := authProvider(f)
+	if err != nil {
+		return err
+	}
+
+	var key string
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		key, err = readSecret(fmt.Sprintf("Enter your %s API key: ", provider))
+		if err != nil {
+			return err
+		}
+	} else {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading API key: %w", err)
+		}
+		key = strings.TrimSpace(line)
+	}
+	if key == "" {
+		return errors.New("no API key given")
+	}
+
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Set(config.CredentialName(provider, f.profile), key); err != nil {
+		return err
+	}
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	fmt.Fprintf(out, "Stored the %s API key %s in the %s\n", keyDescription(provider, f.profile), maskSecret(key), nameStyle.Render(store.String()))
+
+	path := f.config
+	if path == "" {
+		if path, err = config.DefaultUserPath(); err != nil {
+			return err
+		}
+	}
+	removed, err := config.UnsetFileValue(afero.NewOsFs(), path, f.profile, config.APIKeyName(provider))
+	if err != nil {
+		return err
+	}
+	if removed {
+		fmt.Fprintf(out, "Removed the plaintext %s from %s\n", config.APIKeyName(provider), path)
+	}
+	return nil
+}
+
+// runAuthLogout removes the stored API key of a provider
+func runAuthLogout(f authFlags, out io.Writer) error {
+	provider, err := authProvider(f)
+	if err != nil {
+		return err
+	}
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(config.CredentialName(provider, f.profile)); err != nil {
+		if errors.Is(err, credentials.ErrNotFound) {
+			return fmt.Errorf("no %s API key is stored in the %s", keyDescription(provider, f.profile), store)
+		}
+		return err
+	}
+	fmt.Fprintf(out, "Removed the %s API key from the %s\n", keyDescription(provider, f.profile), store)
+	return nil
+}
+
+// keyDescription names the API key of a provider for a profile in messages
+func keyDescription(provider, profile string) string {
+	if profile == "" {
+		return provider
+	}
+	return fmt.Sprintf("%s (profile %s)", provider, profile)
+}
+
+// runAuthStatus prints the API key used for each provider and where it comes from
+func runAuthStatus(f authFlags, out io.Writer) error {
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	req, origins, err := config.Load(config.Options{ConfigPath: f.config, Credentials: store, Profile: f.profile})
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	fmt.Fprintf(out, "Credential store: %s\n\n", store)
+	faint := lipgloss.NewStyle().Faint(true)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, provider := range llm.Providers() {
+		key := config.APIKeyName(provider)
+		value, _ := config.Value(req, key)
+		name := provider
+		if provider == req.Provider {
+			name += " (active)"
+		}
+		secret := fmt.Sprint(value)
+		if secret == "" {
+			fmt.Fprintf(w, "%s:\tnot logged in\n", name)
+			continue
+		}
+		origin := origins[key]
+		line := fmt.Sprintf("%s:\t%s\t%s", name, maskSecret(secret), origin)
+		if origin.Layer == config.LayerUser || origin.Layer == config.LayerProject {
+			line += "\t" + faint.Render(`stored in plaintext, run "boil auth login" to move it to the credential store`)
+		}
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/config"
+	"github.com/santiagomed/boil/conventions"
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/santiagomed/boil/utils"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// manifestLayer is the origin of the configuration values of a batch manifest
+const manifestLayer = "batch manifest"
+
+type batchFlags struct {
+	config  string
+	profile string
+	// concurrency is the number of projects generated at the same time
+	concurrency int
+	output      string
+	onExists    fs.ExistsPolicy
+}
+
+// batchManifest lists the projects of a batch. Each project is a mapping of configuration
+// keys, applied over the defaults, which are applied over the configuration.
+type batchManifest struct {
+	Defaults map[string]interface{}   `yaml:"defaults"`
+	Projects []map[string]interface{} `yaml:"projects"`
+}
+
+// batchProject is a project of a batch and the outcome of generating it
+type batchProject struct {
+	request *core.Request
+	// fs is the file system the project is generated in, created when a worker picks it up
+	fs      *fs.FileSystem
+	dir     string
+	err     error
+	elapsed time.Duration
+}
+
+// loadBatchManifest reads a manifest and resolves the request of each of its projects
+func loadBatchManifest(path string, f batchFlags) ([]*batchProject, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+	var manifest batchManifest
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&manifest); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error parsing manifest %s: %w", path, err)
+	}
+	if len(manifest.Projects) == 0 {
+		return nil, fmt.Errorf("manifest %s has no projects", path)
+	}
+
+	base, origins, err := loadRequest(f.config, f.profile)
+	if err != nil {
+		return nil, err
+	}
+	return batchProjects(path, manifest, base, origins, f)
+}
+
+// batchProjects resolves the request of each project of a manifest over the base request,
+// which is left unchanged
+func batchProjects(path string, manifest batchManifest, base *core.Request, origins config.Origins, f batchFlags) ([]*batchProject, error) {
+	base = base.Clone()
+	if err := config.Apply(base, origins, manifest.Defaults, config.Origin{Layer: manifestLayer, Name: path + " defaults"}); err != nil {
+		return nil, err
+	}
+	if f.output != "" {
+		base.OutputDir = f.output
+	}
+
+	dirs := make(map[string]int)
+	projects := make([]*batchProject, 0, len(manifest.Projects))
+	for i, values := range manifest.Projects {
+		req := base.Clone()
+		origin := config.Origin{Layer: manifestLayer, Name: fmt.Sprintf("%s project %d", path, i+1)}
+		if err := config.Apply(req, config.Origins{}, values, origin); err != nil {
+			return nil, err
+		}
+		if req.ProjectName == "" || req.ProjectDescription == "" {
+			return nil, fmt.Errorf("project %d of %s needs a project_name and a project_description", i+1, path)
+		}
+		if req.ConventionsFile != base.ConventionsFile {
+			found, err := conventions.Find(afero.NewOsFs(), req.ConventionsFile)
+			if err != nil {
+				return nil, err
+			}
+			req.Conventions = found
+		}
+		req.Usage = llm.NewUsageTracker()
+
+		dir := projectDir(req)
+		if j, ok := dirs[dir]; ok {
+			return nil, fmt.Errorf("projects %d and %d of %s are both written to %s", j+1, i+1, path, dir)
+		}
+		dirs[dir] = i
+		projects = append(projects, &batchProject{request: req, dir: dir})
+	}
+	return projects, nil
+}
+
+// checkBatchOutputDirs verifies that the output directory of every project of a batch can
+// be written to
+func checkBatchOutputDirs(dstFS afero.Fs, projects []*batchProject) error {
+	checked := make(map[string]bool)
+	for _, p := range projects {
+		dir := outputDir(p.request.OutputDir)
+		if checked[dir] {
+			continue
+		}
+		checked[dir] = true
+		if err := fs.EnsureWritableDir(dstFS, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBatch generates the projects of a manifest with the engine's worker pool, each in its
+// own file system, writes the successful ones and prints a summary of the results
+func runBatch(path string, f batchFlags, out io.Writer) error {
+	if f.onExists == fs.ExistsReview {
+		return errors.New("--on-exists review is not supported in batches")
+	}
+	projects, err := loadBatchManifest(path, f)
+	if err != nil {
+		return err
+	}
+	// Refuse unusable output directories before any project is generated
+	osFs := afero.NewOsFs()
+	if err := checkBatchOutputDirs(osFs, projects); err != nil {
+		return err
+	}
+
+	pub := &batchPublisher{out: out, names: make(map[string]string, len(projects))}
+	for _, p := range projects {
+		pub.names[p.dir] = p.request.ProjectName
+	}
+	InitLogger()
+	engine, err := NewProjectEngine(pub, GetLogger(), f.concurrency, nil, "http://localhost:8000")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine.Start(ctx)
+	defer engine.Shutdown(5 * time.Second)
+
+	type result struct {
+		project *batchProject
+		err     error
+	}
+	results := make(chan result, len(projects))
+	pending := 0
+	for _, p := range projects {
+		p := p
+		// Refuse early, before any LLM calls, if the project could not be written
+		if err := fs.CheckOutputDir(osFs, p.dir, f.onExists); err != nil {
+			p.err = err
+			continue
+		}
+		pending++
+		start := time.Now()
+		resultChan := engine.Submit(ExecutionRequest{
+			ID:      p.dir,
+			Request: p.request,
+			NewFS: func() *fs.FileSystem {
+				p.fs = fs.NewMemoryFileSystem()
+				return p.fs
+			},
+		})
+		go func() {
+			err := <-resultChan
+			p.elapsed = time.Since(start)
+			results <- result{p, err}
+		}()
+	}
+	pub.printf("Generating %d %s, %d at a time...\n", pending, plural(pending, "project", "projects"), f.concurrency)
+
+	ok := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("✓")
+	failed := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render("✗")
+	for ; pending > 0; pending-- {
+		r := <-results
+		r.project.err = r.err
+		if r.err == nil {
+			_, r.project.err = r.project.fs.WriteProject(osFs, ".", r.project.dir, f.onExists)
+		}
+		if r.project.err != nil {
+			pub.printf("%s %s: %v\n", failed, r.project.request.ProjectName, r.project.err)
+			continue
+		}
+		pub.printf("%s %s written to %s\n", ok, r.project.request.ProjectName, r.project.dir)
+	}
+
+	fmt.Fprintln(out)
+	if err := writeBatchSummary(out, projects); err != nil {
+		return err
+	}
+	failures := 0
+	for _, p := range projects {
+		if p.err != nil {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d projects failed", failures, len(projects))
+	}
+	return nil
+}
+
+// batchPublisher prints the events of the projects of a batch as they happen, labelled with
+// their project. The requests of a batch are identified by their output directory.
+type batchPublisher struct {
+	core.DefaultStepPublisher
+	mu  sync.Mutex
+	out io.Writer
+	// names are the project names by request ID
+	names map[string]string
+}
+
+func (p *batchPublisher) PublishRequestStep(requestID string, step core.StepType) {}
+
+func (p *batchPublisher) RequestError(requestID string, step core.StepType, err error) {}
+
+func (p *batchPublisher) PublishRequestEvent(requestID string, event core.Event) {
+	p.printf("%s %s: %s\n", eventMark(event), p.names[requestID], event.Message)
+}
+
+// printf prints to the output of the batch, which the workers share
+func (p *batchPublisher) printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, format, args...)
+}
+
+// writeBatchSummary prints a table of the results of a batch
+func writeBatchSummary(out io.Writer, projects []*batchProject) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tSTATUS\tTIME\tTOKENS\tCOST\tOUTPUT")
+	var total llm.Usage
+	for _, p := range projects {
+		usage := llm.TotalUsage(p.request.Usage.Usage())
+		total = llm.TotalUsage([]llm.Usage{total, usage})
+
+		status, output := "ok", p.dir
+		if p.err != nil {
+			status, output = "failed", firstLine(p.err.Error())
+		}
+		elapsed := "-"
+		if p.elapsed > 0 {
+			elapsed = p.elapsed.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", utils.FormatProjectName(p.request.ProjectName), status, elapsed, usage.InputTokens+usage.OutputTokens, formatBatchCost(usage.Cost), output)
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t%d\t%s\t\n", total.InputTokens+total.OutputTokens, formatBatchCost(total.Cost))
+	return w.Flush()
+}
+
+// formatBatchCost formats an estimated cost for the summary table
+func formatBatchCost(cost float64) string {
+	if cost == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%.4f", cost)
+}
+
+// firstLine returns the first line of a message
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
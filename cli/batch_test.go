@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/santiagomed/boil/config"
+	"github.com/santiagomed/boil/core"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchProjects_Fallbacks(t *testing.T) {
+	base := core.DefaultRequest()
+	base.Fallbacks = []string{"openai/a", "openai/b"}
+	manifest := batchManifest{
+		Projects: []map[string]interface{}{
+			{"project_name": "users", "project_description": "Users API", "fallbacks": []interface{}{"anthropic/x"}},
+			{"project_name": "billing", "project_description": "Billing worker", "fallbacks": []interface{}{"anthropic/y", "openai/c", "openai/d"}},
+			{"project_name": "search", "project_description": "Search API"},
+		},
+	}
+
+	projects, err := batchProjects("services.yaml", manifest, base, make(config.Origins), batchFlags{})
+	require.NoError(t, err)
+	require.Len(t, projects, 3)
+	assert.Equal(t, []string{"anthropic/x"}, projects[0].request.Fallbacks)
+	assert.Equal(t, []string{"anthropic/y", "openai/c", "openai/d"}, projects[1].request.Fallbacks)
+	assert.Equal(t, []string{"openai/a", "openai/b"}, projects[2].request.Fallbacks)
+	assert.Equal(t, []string{"openai/a", "openai/b"}, base.Fallbacks)
+}
+
+func TestCheckBatchOutputDirs(t *testing.T) {
+	projects := []*batchProject{
+		{request: &core.Request{ProjectName: "users", OutputDir: "services"}},
+		{request: &core.Request{ProjectName: "billing", OutputDir: "workers"}},
+	}
+
+	dst := afero.NewMemMapFs()
+	require.NoError(t, checkBatchOutputDirs(dst, projects))
+	for _, dir := range []string{"services", "workers"} {
+		exists, err := afero.DirExists(dst, dir)
+		require.NoError(t, err)
+		assert.True(t, exists, dir)
+	}
+
+	require.NoError(t, afero.WriteFile(dst, "file", []byte("x"), 0o644))
+	projects[1].request.OutputDir = "file"
+	assert.ErrorContains(t, checkBatchOutputDirs(dst, projects), "file")
+	assert.Error(t, checkBatchOutputDirs(afero.NewReadOnlyFs(afero.NewMemMapFs()), projects))
+}
@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/templates"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +18,12 @@ var rootCmd = &cobra.Command{
 	Use:   "boil",
 	Short: "Boil is a tool for generating project boilerplate files",
 	Long:  `Boil is a powerful tool that uses AI to generate custom project boilerplate files based on your description.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := loadPrompts(); err != nil {
+			fmt.Printf("Error loading prompts: %v\n", err)
+			os.Exit(1)
+		}
+	},
 }
 
 var genCmd = &cobra.Command{
@@ -32,6 +42,39 @@ var genCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		var opts []tea.ProgramOption
+		if flags.archive == "-" {
+			// Keep stdout clean for the archive
+			opts = append(opts, tea.WithOutput(os.Stderr))
+		}
+
+		p := tea.NewProgram(model, opts...)
+		if _, err := p.Run(); err != nil {
+			fmt.Printf("Error running program: %v\n", err)
+			os.Exit(1)
+		}
+
+		model.Shutdown()
+	},
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add <description>",
+	Short: "Add a new service or module to an existing repository",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseAddFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		model, err := newAddModel(flags, args[0])
+		if err != nil {
+			fmt.Printf("Error initializing model: %v\n", err)
+			os.Exit(1)
+		}
+
 		p := tea.NewProgram(model)
 		if _, err := p.Run(); err != nil {
 			fmt.Printf("Error running program: %v\n", err)
@@ -42,6 +85,60 @@ var genCmd = &cobra.Command{
 	},
 }
 
+var regenCmd = &cobra.Command{
+	Use:   "regen <file>",
+	Short: "Regenerate a single file of a generated project",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseRegenFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runRegen(flags, args[0], os.Stdin, os.Stdout); err != nil {
+			fmt.Printf("Error regenerating file: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <manifest.yaml>",
+	Short: "Generate the projects listed in a manifest file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseBatchFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runBatch(args[0], flags, os.Stdout); err != nil {
+			fmt.Printf("Error generating batch: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an HTTP API to generate projects",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseServeFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runServe(flags); err != nil {
+			fmt.Printf("Error serving: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Get information about existing projects",
@@ -53,6 +150,17 @@ var getCmd = &cobra.Command{
 		}
 		var p *tea.Program
 		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFBA08"))
+
+		outputDir, err := resolveGetOutputDir(flags)
+		if err != nil {
+			fmt.Println(errorStyle.Render(err.Error()))
+			os.Exit(1)
+		}
+		if err := fs.EnsureWritableDir(afero.NewOsFs(), outputDir); err != nil {
+			fmt.Println(errorStyle.Render(err.Error()))
+			os.Exit(1)
+		}
+
 		url := "https://api.boil.sh/project/download"
 		resp, err := downloadFile(url, flags.token)
 		if err != nil {
@@ -84,7 +192,7 @@ var getCmd = &cobra.Command{
 			},
 		}
 
-		m := newGetCmdModel(pw, path)
+		m := newGetCmdModel(pw, path, outputDir)
 
 		p = tea.NewProgram(m)
 
@@ -97,14 +205,355 @@ var getCmd = &cobra.Command{
 	},
 }
 
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage project templates",
+	Long:  `Templates are named presets for "boil gen --template <name>", stored in ~/.boil/templates.`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the saved templates",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplateList(os.Stdout); err != nil {
+			fmt.Printf("Error listing templates: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a saved template",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplateShow(args[0], os.Stdout); err != nil {
+			fmt.Printf("Error showing template: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templateSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save a template, optionally from a generated project",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseTemplateSaveFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runTemplateSave(flags, args[0], os.Stdout); err != nil {
+			fmt.Printf("Error saving template: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templateDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a saved template",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplateDelete(args[0], os.Stdout); err != nil {
+			fmt.Printf("Error deleting template: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "Manage the prompt templates",
+	Long:  `Prompt templates in ~/.boil/prompts/<name>.tmpl override the default prompts. They use Go text/template syntax and are validated when Boil starts.`,
+	// Prompt overrides are not loaded, so invalid ones can be replaced
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var promptsDumpCmd = &cobra.Command{
+	Use:   "dump [dir]",
+	Short: "Write the default prompt templates to a directory for editing (default: ~/.boil/prompts)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parsePromptsDumpFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		var dir string
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if err := runPromptsDump(flags, dir, os.Stdout); err != nil {
+			fmt.Printf("Error dumping prompts: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit the configuration",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up the configuration interactively",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseConfigInitFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runConfigInit(flags, os.Stdout); err != nil {
+			fmt.Printf("Error initializing config: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a configuration key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseConfigGetFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runConfigGet(flags, args[0], os.Stdout); err != nil {
+			fmt.Printf("Error getting config: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration key in the user configuration file (~/.boil/config.yaml)",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseConfigSetFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runConfigSet(flags, args[0], args[1], os.Stdout); err != nil {
+			fmt.Printf("Error setting config: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration files for unknown keys, invalid values and unknown models",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseConfigValidateFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runConfigValidate(flags, os.Stdout); err != nil {
+			fmt.Printf("Error validating config: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseConfigShowFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runConfigShow(flags, os.Stdout); err != nil {
+			fmt.Printf("Error showing config: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the API keys in the credential store",
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store a provider's API key in the credential store",
+	Long:  "Store a provider's API key in the OS keyring, or in an encrypted file if no keyring is available. The key is read from the terminal, or from stdin if it isn't one.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseAuthFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runAuthLogin(flags, os.Stdin, os.Stdout); err != nil {
+			fmt.Printf("Error logging in: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove a provider's API key from the credential store",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseAuthFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runAuthLogout(flags, os.Stdout); err != nil {
+			fmt.Printf("Error logging out: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the API key used for each provider and where it comes from",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags, err := parseAuthFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing flags: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := runAuthStatus(flags, os.Stdout); err != nil {
+			fmt.Printf("Error getting auth status: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(genCmd)
 	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(regenCmd)
+	rootCmd.AddCommand(batchCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateSaveCmd)
+	templateCmd.AddCommand(templateDeleteCmd)
+	rootCmd.AddCommand(promptsCmd)
+	promptsCmd.AddCommand(promptsDumpCmd)
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authStatusCmd)
 
 	genCmd.Flags().StringP("name", "n", "", "The name of the project to generate. Also used as the project directory name")
 	genCmd.Flags().StringP("config", "c", "", "Path to custom configuration file")
+	genCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	genCmd.Flags().StringP("template", "t", "", "Name of a saved template to generate the project from (see \"boil template list\")")
+	genCmd.Flags().StringP("output", "o", "", "Directory to write the project to (default: current directory, or output_dir from the config)")
+	genCmd.Flags().String("archive", "", "Write the project to a .zip or .tar.gz archive instead of a directory (\"-\" for stdout)")
+	genCmd.Flags().String("archive-format", "", "Archive format: zip or tar.gz (default: inferred from the archive path, zip for stdout)")
+	genCmd.Flags().String("on-exists", string(fs.ExistsFail), "What to do if the project directory already exists: fail, overwrite or merge (replace the generated files, keeping all others), backup or review")
+	genCmd.Flags().Bool("verify", false, "Build the generated project in a temporary directory before writing it")
+	genCmd.Flags().String("verify-command", "", "Command used to verify the project (default: inferred from go.mod, package.json, Cargo.toml or pyproject.toml)")
+	genCmd.Flags().Bool("repair", false, "Regenerate files that fail to build with the build errors as context, until the build passes")
+	genCmd.Flags().Int("repair-rounds", 0, "Maximum number of repair rounds (default: repair_rounds from the config, or 3)")
+
+	addCmd.Flags().String("into", ".", "Path to the existing repository to add the project to")
+	addCmd.Flags().StringP("name", "n", "", "The name of the project to add")
+	addCmd.Flags().StringP("config", "c", "", "Path to custom configuration file")
+	addCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	addCmd.Flags().Bool("allow-overwrite", false, "Allow generating files that already exist in the repository")
+
+	regenCmd.Flags().String("hint", "", "Instructions for regenerating the file")
+	regenCmd.Flags().StringP("project", "p", "", "Project directory (default: closest directory with saved project metadata, or the current directory)")
+	regenCmd.Flags().StringP("config", "c", "", "Path to custom configuration file")
+	regenCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	regenCmd.Flags().BoolP("yes", "y", false, "Write the new content without asking for confirmation")
+
+	batchCmd.Flags().StringP("config", "c", "", "Path to custom configuration file")
+	batchCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	batchCmd.Flags().IntP("concurrency", "j", 2, "Number of projects generated at the same time")
+	batchCmd.Flags().StringP("output", "o", "", "Directory to write the projects to (default: current directory, or output_dir from the config)")
+	batchCmd.Flags().String("on-exists", string(fs.ExistsFail), "What to do if a project directory already exists: fail, overwrite or merge (replace the generated files, keeping all others) or backup")
+
+	serveCmd.Flags().String("addr", ":8080", "Address to listen on")
+	serveCmd.Flags().StringP("config", "c", "", "Path to custom configuration file")
+	serveCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	serveCmd.Flags().IntP("concurrency", "j", 2, "Number of jobs run at the same time")
+	serveCmd.Flags().String("jobs-dir", "", "Directory to save jobs and their projects to (default: ~/.boil/jobs)")
+	serveCmd.Flags().String("token", "", "Bearer token required on every request (default: $BOIL_SERVE_TOKEN)")
+
+	templateSaveCmd.Flags().String("from", "", "Generated project to take the description, model and file tree from")
+	templateSaveCmd.Flags().String("description", "", "Project description (asked for when generating if empty)")
+	templateSaveCmd.Flags().String("instructions", "", "Extra instructions added to the project description")
+	templateSaveCmd.Flags().String("model", "", "Model used to generate projects from the template")
+	templateSaveCmd.Flags().String("components", "", "Optional components to generate: comma-separated git, gitignore, readme, dockerfile, or none (asked for when generating if unset)")
+	templateSaveCmd.Flags().Bool("pin-tree", false, "Keep the file tree of the --from project instead of generating one")
+	templateSaveCmd.Flags().BoolP("force", "f", false, "Replace an existing template with the same name")
+
+	configShowCmd.Flags().StringP("config", "c", "", "Path to custom configuration file, used instead of ~/.boil/config.yaml")
+	configShowCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	configShowCmd.Flags().Bool("origin", false, "Show where each value comes from: default, user config, project config, env or flag")
+
+	configInitCmd.Flags().StringP("config", "c", "", "Path to the configuration file to write, instead of ~/.boil/config.yaml")
+
+	configGetCmd.Flags().StringP("config", "c", "", "Path to custom configuration file, used instead of ~/.boil/config.yaml")
+	configGetCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+	configGetCmd.Flags().Bool("origin", false, "Show where the value comes from")
+
+	configSetCmd.Flags().StringP("config", "c", "", "Path to the configuration file to write, instead of ~/.boil/config.yaml")
+	configSetCmd.Flags().String("profile", "", "Set the key in this profile instead of the base configuration")
+	configSetCmd.Flags().Bool("project", false, "Write the project configuration file (.boil.yaml) instead")
+
+	configValidateCmd.Flags().StringP("config", "c", "", "Path to custom configuration file, used instead of ~/.boil/config.yaml")
+	configValidateCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+
+	authLoginCmd.Flags().StringP("provider", "p", "", "Provider of the API key: openai or anthropic (default: provider from the config)")
+	authLoginCmd.Flags().StringP("config", "c", "", "Path to custom configuration file, used instead of ~/.boil/config.yaml")
+	authLoginCmd.Flags().String("profile", "", "Profile the API key is for, used instead of the provider's key when the profile is selected")
+
+	authLogoutCmd.Flags().StringP("provider", "p", "", "Provider of the API key: openai or anthropic (default: provider from the config)")
+	authLogoutCmd.Flags().StringP("config", "c", "", "Path to custom configuration file, used instead of ~/.boil/config.yaml")
+	authLogoutCmd.Flags().String("profile", "", "Profile the API key is for, used instead of the provider's key when the profile is selected")
+
+	authStatusCmd.Flags().StringP("config", "c", "", "Path to custom configuration file, used instead of ~/.boil/config.yaml")
+	authStatusCmd.Flags().String("profile", "", "Profile of the configuration to use (default: $BOIL_PROFILE, or default_profile from the config)")
+
+	promptsDumpCmd.Flags().BoolP("force", "f", false, "Overwrite existing prompt templates")
 
 	getCmd.Flags().StringP("token", "t", "", "Boil API token")
+	getCmd.Flags().StringP("output", "o", "", "Directory to save the project to (default: current directory, or output_dir from the config)")
+	getCmd.Flags().StringP("config", "c", "", "Path to custom configuration file")
 	getCmd.MarkFlagRequired("token")
 }
 
@@ -114,8 +563,281 @@ func parseGetFlags(cmd *cobra.Command) (getFlags, error) {
 		return getFlags{}, err
 	}
 
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return getFlags{}, err
+	}
+
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return getFlags{}, err
+	}
+
 	return getFlags{
-		token: token,
+		token:  token,
+		output: output,
+		config: config,
+	}, nil
+}
+
+func parseAddFlags(cmd *cobra.Command) (addFlags, error) {
+	into, err := cmd.Flags().GetString("into")
+	if err != nil {
+		return addFlags{}, err
+	}
+
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return addFlags{}, err
+	}
+
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return addFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return addFlags{}, err
+	}
+
+	allowOverwrite, err := cmd.Flags().GetBool("allow-overwrite")
+	if err != nil {
+		return addFlags{}, err
+	}
+
+	return addFlags{
+		into:           into,
+		name:           name,
+		config:         config,
+		profile:        profile,
+		allowOverwrite: allowOverwrite,
+	}, nil
+}
+
+func parseRegenFlags(cmd *cobra.Command) (regenFlags, error) {
+	hint, err := cmd.Flags().GetString("hint")
+	if err != nil {
+		return regenFlags{}, err
+	}
+
+	project, err := cmd.Flags().GetString("project")
+	if err != nil {
+		return regenFlags{}, err
+	}
+
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return regenFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return regenFlags{}, err
+	}
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return regenFlags{}, err
+	}
+
+	return regenFlags{
+		hint:    hint,
+		project: project,
+		config:  config,
+		profile: profile,
+		yes:     yes,
+	}, nil
+}
+
+func parseTemplateSaveFlags(cmd *cobra.Command) (templateSaveFlags, error) {
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return templateSaveFlags{}, err
+	}
+
+	description, err := cmd.Flags().GetString("description")
+	if err != nil {
+		return templateSaveFlags{}, err
+	}
+
+	instructions, err := cmd.Flags().GetString("instructions")
+	if err != nil {
+		return templateSaveFlags{}, err
+	}
+
+	model, err := cmd.Flags().GetString("model")
+	if err != nil {
+		return templateSaveFlags{}, err
+	}
+
+	var components *templates.Components
+	if cmd.Flags().Changed("components") {
+		value, err := cmd.Flags().GetString("components")
+		if err != nil {
+			return templateSaveFlags{}, err
+		}
+		components, err = parseComponents(value)
+		if err != nil {
+			return templateSaveFlags{}, err
+		}
+	}
+
+	pinTree, err := cmd.Flags().GetBool("pin-tree")
+	if err != nil {
+		return templateSaveFlags{}, err
+	}
+	if pinTree && from == "" {
+		return templateSaveFlags{}, errors.New("--pin-tree requires --from")
+	}
+
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return templateSaveFlags{}, err
+	}
+
+	return templateSaveFlags{
+		from:         from,
+		description:  description,
+		instructions: instructions,
+		model:        model,
+		components:   components,
+		pinTree:      pinTree,
+		force:        force,
+	}, nil
+}
+
+func parseConfigShowFlags(cmd *cobra.Command) (configShowFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return configShowFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return configShowFlags{}, err
+	}
+
+	origin, err := cmd.Flags().GetBool("origin")
+	if err != nil {
+		return configShowFlags{}, err
+	}
+
+	return configShowFlags{
+		config:  config,
+		profile: profile,
+		origin:  origin,
+	}, nil
+}
+
+func parseConfigInitFlags(cmd *cobra.Command) (configInitFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return configInitFlags{}, err
+	}
+
+	return configInitFlags{
+		config: config,
+	}, nil
+}
+
+func parseConfigGetFlags(cmd *cobra.Command) (configGetFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return configGetFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return configGetFlags{}, err
+	}
+
+	origin, err := cmd.Flags().GetBool("origin")
+	if err != nil {
+		return configGetFlags{}, err
+	}
+
+	return configGetFlags{
+		config:  config,
+		profile: profile,
+		origin:  origin,
+	}, nil
+}
+
+func parseConfigSetFlags(cmd *cobra.Command) (configSetFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return configSetFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return configSetFlags{}, err
+	}
+
+	project, err := cmd.Flags().GetBool("project")
+	if err != nil {
+		return configSetFlags{}, err
+	}
+
+	return configSetFlags{
+		config:  config,
+		profile: profile,
+		project: project,
+	}, nil
+}
+
+func parseConfigValidateFlags(cmd *cobra.Command) (configValidateFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return configValidateFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return configValidateFlags{}, err
+	}
+
+	return configValidateFlags{
+		config:  config,
+		profile: profile,
+	}, nil
+}
+
+func parseAuthFlags(cmd *cobra.Command) (authFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return authFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return authFlags{}, err
+	}
+
+	var provider string
+	if cmd.Flags().Lookup("provider") != nil {
+		provider, err = cmd.Flags().GetString("provider")
+		if err != nil {
+			return authFlags{}, err
+		}
+	}
+
+	return authFlags{
+		provider: provider,
+		config:   config,
+		profile:  profile,
+	}, nil
+}
+
+func parsePromptsDumpFlags(cmd *cobra.Command) (promptsDumpFlags, error) {
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return promptsDumpFlags{}, err
+	}
+
+	return promptsDumpFlags{
+		force: force,
 	}, nil
 }
 
@@ -130,12 +852,188 @@ func parseGenFlags(cmd *cobra.Command) (genFlags, error) {
 		return genFlags{}, err
 	}
 
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	template, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	onExists, err := cmd.Flags().GetString("on-exists")
+	if err != nil {
+		return genFlags{}, err
+	}
+	policy, err := fs.ParseExistsPolicy(onExists)
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	archive, err := cmd.Flags().GetString("archive")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	archiveFormat, err := parseArchiveFormat(cmd, archive)
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	verify, err := cmd.Flags().GetBool("verify")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	verifyCommand, err := cmd.Flags().GetString("verify-command")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	repair, err := cmd.Flags().GetBool("repair")
+	if err != nil {
+		return genFlags{}, err
+	}
+
+	repairRounds, err := cmd.Flags().GetInt("repair-rounds")
+	if err != nil {
+		return genFlags{}, err
+	}
+	if repairRounds < 0 {
+		return genFlags{}, fmt.Errorf("--repair-rounds must not be negative")
+	}
+
 	return genFlags{
-		name:   name,
-		config: config,
+		name:          name,
+		config:        config,
+		profile:       profile,
+		template:      template,
+		output:        output,
+		onExists:      policy,
+		archive:       archive,
+		archiveFormat: archiveFormat,
+		verify:        verify,
+		verifyCommand: verifyCommand,
+		repair:        repair,
+		repairRounds:  repairRounds,
+	}, nil
+}
+
+func parseBatchFlags(cmd *cobra.Command) (batchFlags, error) {
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return batchFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return batchFlags{}, err
+	}
+
+	concurrency, err := cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return batchFlags{}, err
+	}
+	if concurrency < 1 {
+		return batchFlags{}, fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return batchFlags{}, err
+	}
+
+	onExists, err := cmd.Flags().GetString("on-exists")
+	if err != nil {
+		return batchFlags{}, err
+	}
+	policy, err := fs.ParseExistsPolicy(onExists)
+	if err != nil {
+		return batchFlags{}, err
+	}
+
+	return batchFlags{
+		config:      config,
+		profile:     profile,
+		concurrency: concurrency,
+		output:      output,
+		onExists:    policy,
+	}, nil
+}
+
+func parseServeFlags(cmd *cobra.Command) (serveFlags, error) {
+	addr, err := cmd.Flags().GetString("addr")
+	if err != nil {
+		return serveFlags{}, err
+	}
+
+	config, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return serveFlags{}, err
+	}
+
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return serveFlags{}, err
+	}
+
+	concurrency, err := cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return serveFlags{}, err
+	}
+	if concurrency < 1 {
+		return serveFlags{}, fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	jobsDir, err := cmd.Flags().GetString("jobs-dir")
+	if err != nil {
+		return serveFlags{}, err
+	}
+
+	token, err := cmd.Flags().GetString("token")
+	if err != nil {
+		return serveFlags{}, err
+	}
+
+	return serveFlags{
+		addr:        addr,
+		config:      config,
+		profile:     profile,
+		concurrency: concurrency,
+		jobsDir:     jobsDir,
+		token:       token,
 	}, nil
 }
 
+// parseArchiveFormat returns the archive format from the --archive-format flag, or infers
+// it from the archive path. Archives written to stdout default to zip.
+func parseArchiveFormat(cmd *cobra.Command, archive string) (fs.ArchiveFormat, error) {
+	format, err := cmd.Flags().GetString("archive-format")
+	if err != nil {
+		return "", err
+	}
+	if format != "" {
+		if archive == "" {
+			return "", fmt.Errorf("--archive-format requires --archive")
+		}
+		return fs.ParseArchiveFormat(format)
+	}
+	switch archive {
+	case "":
+		return "", nil
+	case "-":
+		return fs.ArchiveZip, nil
+	default:
+		return fs.ArchiveFormatFromPath(archive)
+	}
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/config"
+	"github.com/spf13/afero"
+)
+
+type configShowFlags struct {
+	config  string
+	profile string
+	origin  bool
+}
+
+type configGetFlags struct {
+	config  string
+	profile string
+	origin  bool
+}
+
+type configSetFlags struct {
+	// config is the file to write instead of ~/.boil/config.yaml
+	config  string
+	profile string
+	// project writes the project configuration file instead of the user's
+	project bool
+}
+
+type configValidateFlags struct {
+	config  string
+	profile string
+}
+
+// runConfigShow prints the effective configuration, and where each value comes from if
+// f.origin is set
+func runConfigShow(f configShowFlags, out io.Writer) error {
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	req, origins, err := config.Load(config.Options{ConfigPath: f.config, Credentials: store, Profile: f.profile})
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, key := range config.Keys() {
+		value, _ := config.Value(req, key)
+		line := fmt.Sprintf("%s:\t%s", key, formatConfigValue(key, value))
+		if f.origin {
+			line += "\t" + origins[key].String()
+		}
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+// runConfigGet prints the effective value of a configuration key
+func runConfigGet(f configGetFlags, key string, out io.Writer) error {
+	if !config.IsKey(key) {
+		return fmt.Errorf("unknown configuration key %q (see \"boil config show\" for the keys)", key)
+	}
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	req, origins, err := config.Load(config.Options{ConfigPath: f.config, Credentials: store, Profile: f.profile})
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	value, _ := config.Value(req, key)
+	line := formatConfigValue(key, value)
+	if f.origin {
+		line += "\t" + origins[key].String()
+	}
+	fmt.Fprintln(out, line)
+	return nil
+}
+
+// runConfigSet writes a configuration key to the user configuration file, or to the project
+// configuration file if f.project is set
+func runConfigSet(f configSetFlags, key, value string, out io.Writer) error {
+	path, err := configSetPath(f)
+	if err != nil {
+		return err
+	}
+	if err := config.SetFileValue(afero.NewOsFs(), path, f.profile, key, value); err != nil {
+		return err
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	fmt.Fprintf(out, "Set %s to %s in %s\n", key, formatConfigValue(key, value), nameStyle.Render(path))
+	if strings.HasSuffix(key, "api_key") {
+		faint := lipgloss.NewStyle().Faint(true)
+		fmt.Fprintln(out, faint.Render(`The key is stored in plaintext. Use "boil auth login" to keep it in the credential store instead.`))
+	}
+	return nil
+}
+
+// configSetPath returns the configuration file written by "config set"
+func configSetPath(f configSetFlags) (string, error) {
+	switch {
+	case f.project && f.config != "":
+		return "", fmt.Errorf("--project and --config can't be used together")
+	case f.project:
+		return config.ProjectPath, nil
+	case f.config != "":
+		return f.config, nil
+	default:
+		return config.DefaultUserPath()
+	}
+}
+
+// runConfigValidate checks the user and project configuration files and the effective
+// provider and model. It returns an error if any problem is found.
+func runConfigValidate(f configValidateFlags, out io.Writer) error {
+	osFs := afero.NewOsFs()
+	userPath := f.config
+	if userPath == "" {
+		var err error
+		userPath, err = config.DefaultUserPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	var problems []config.Problem
+	checked := 0
+	for _, path := range []string{userPath, config.ProjectPath} {
+		fileProblems, err := config.ValidateFile(osFs, path)
+		if err != nil {
+			if os.IsNotExist(err) && f.config != path {
+				continue
+			}
+			return err
+		}
+		checked++
+		problems = append(problems, fileProblems...)
+	}
+
+	// Type errors are already reported for each file
+	if len(problems) == 0 {
+		req, _, err := config.Load(config.Options{Fs: osFs, ConfigPath: f.config, Profile: f.profile})
+		if err != nil {
+			// Such as an unknown profile or an invalid BOIL_ environment variable
+			problems = append(problems, config.Problem{Message: err.Error()})
+		} else {
+			problems = append(problems, config.ValidateRequest(req)...)
+		}
+	}
+
+	if len(problems) == 0 {
+		checkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+		fmt.Fprintf(out, "%s Configuration is valid (%d %s checked)\n", checkStyle.Render("✓"), checked, plural(checked, "file", "files"))
+		return nil
+	}
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	for _, p := range problems {
+		fmt.Fprintf(out, "%s %s\n", errStyle.Render("✗"), p)
+	}
+	return fmt.Errorf("found %d configuration %s", len(problems), plural(len(problems), "problem", "problems"))
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// formatConfigValue formats a configuration value for display, hiding secrets
+func formatConfigValue(key string, value interface{}) string {
+	if list, ok := value.([]string); ok {
+		return "[" + strings.Join(list, ", ") + "]"
+	}
+	s := fmt.Sprint(value)
+	if strings.HasSuffix(key, "api_key") && s != "" {
+		return maskSecret(s)
+	}
+	if s == "" {
+		return `""`
+	}
+	if strings.Contains(s, "\n") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// maskSecret hides all but the last four characters of a secret
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
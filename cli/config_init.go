@@ -0,0 +1,295 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/config"
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+)
+
+type configInitFlags struct {
+	// config is the file to write instead of ~/.boil/config.yaml
+	config string
+}
+
+type configInitStep int
+
+const (
+	initProvider configInitStep = iota
+	initAPIKey
+	initModel
+	initGitRepo
+	initReadme
+	initDockerfile
+	initDone
+)
+
+// configInitModel is the "config init" wizard. It asks one question per step and collects
+// the answers as configuration values.
+type configInitModel struct {
+	step      configInitStep
+	options   []string
+	cursor    int
+	textInput textinput.Model
+	// current is the configuration before the wizard, used for the default answers
+	current *core.Request
+	values  map[string]string
+	aborted bool
+}
+
+func newConfigInitModel(current *core.Request) configInitModel {
+	ti := textinput.New()
+	ti.EchoMode = textinput.EchoPassword
+	ti.EchoCharacter = '•'
+	ti.Width = 60
+
+	m := configInitModel{
+		textInput: ti,
+		current:   current,
+		values:    make(map[string]string),
+	}
+	m.enterStep(initProvider)
+	return m
+}
+
+func (m configInitModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m configInitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch keyMsg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.aborted = true
+		return m, tea.Quit
+	case tea.KeyEnter:
+		m.answer()
+		if m.step == initDone {
+			return m, tea.Quit
+		}
+		if m.step == initAPIKey {
+			return m, textinput.Blink
+		}
+		return m, nil
+	}
+
+	if m.step == initAPIKey {
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
+	switch keyMsg.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.options)-1 {
+			m.cursor++
+		}
+	}
+	return m, nil
+}
+
+func (m configInitModel) View() string {
+	if m.step == initDone {
+		return ""
+	}
+	questionStyle := lipgloss.NewStyle().Bold(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	faint := lipgloss.NewStyle().Faint(true)
+
+	var b strings.Builder
+	b.WriteString(questionStyle.Render(m.question()) + "\n\n")
+	if m.step == initAPIKey {
+		b.WriteString(m.textInput.View() + "\n\n")
+		b.WriteString(faint.Render("(press enter to continue, leave empty to keep the current key, esc to quit)"))
+		return b.String()
+	}
+	for i, option := range m.options {
+		if i == m.cursor {
+			b.WriteString(selectedStyle.Render("> "+option) + "\n")
+		} else {
+			b.WriteString("  " + option + "\n")
+		}
+	}
+	b.WriteString("\n" + faint.Render("(use ↑/↓ to choose, enter to select, esc to quit)"))
+	return b.String()
+}
+
+func (m configInitModel) question() string {
+	switch m.step {
+	case initProvider:
+		return "Which LLM provider do you want to use?"
+	case initAPIKey:
+		return fmt.Sprintf("Enter your %s API key:", m.values["provider"])
+	case initModel:
+		return "Which model should be used by default?"
+	case initGitRepo:
+		return "Initialize a git repository in new projects?"
+	case initReadme:
+		return "Generate a README in new projects?"
+	case initDockerfile:
+		return "Generate a Dockerfile in new projects?"
+	default:
+		return ""
+	}
+}
+
+// answer records the answer to the current step and moves to the next one
+func (m *configInitModel) answer() {
+	switch m.step {
+	case initProvider:
+		m.values["provider"] = m.options[m.cursor]
+	case initAPIKey:
+		if key := strings.TrimSpace(m.textInput.Value()); key != "" {
+			m.values[config.APIKeyName(m.values["provider"])] = key
+		}
+		m.textInput.Blur()
+	case initModel:
+		m.values["model_name"] = m.options[m.cursor]
+	case initGitRepo:
+		m.values["git_repo"] = strconv.FormatBool(m.cursor == 0)
+	case initReadme:
+		m.values["readme"] = strconv.FormatBool(m.cursor == 0)
+	case initDockerfile:
+		m.values["dockerfile"] = strconv.FormatBool(m.cursor == 0)
+	}
+	m.enterStep(m.step + 1)
+}
+
+// enterStep sets up the options of a step, selecting the current configuration's value
+func (m *configInitModel) enterStep(step configInitStep) {
+	m.step = step
+	m.options = nil
+	m.cursor = 0
+
+	yesNo := func(current bool) {
+		m.options = []string{"Yes", "No"}
+		if !current {
+			m.cursor = 1
+		}
+	}
+	switch step {
+	case initProvider:
+		m.options = llm.Providers()
+		m.cursor = indexOf(m.options, m.current.Provider)
+	case initAPIKey:
+		m.textInput.SetValue("")
+		m.textInput.Placeholder = "API key"
+		if current := m.currentAPIKey(); current != "" {
+			m.textInput.Placeholder = "current key " + maskSecret(current)
+		}
+		m.textInput.Focus()
+	case initModel:
+		provider := m.values["provider"]
+		m.options = llm.Models(provider)
+		if provider == m.current.Provider {
+			if i := indexOf(m.options, m.current.ModelName); i >= 0 {
+				m.cursor = i
+			} else if m.current.ModelName != "" {
+				// Keep a custom model available
+				m.options = append([]string{m.current.ModelName}, m.options...)
+			}
+		}
+	case initGitRepo:
+		yesNo(m.current.GitRepo)
+	case initReadme:
+		yesNo(m.current.Readme)
+	case initDockerfile:
+		yesNo(m.current.Dockerfile)
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// currentAPIKey returns the configured API key of the chosen provider
+func (m *configInitModel) currentAPIKey() string {
+	req := *m.current
+	req.Provider = m.values["provider"]
+	return req.ProviderAPIKey()
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// configInitKeys is the order the wizard's answers are written in. API keys go to the
+// credential store instead.
+var configInitKeys = []string{"provider", "model_name", "git_repo", "readme", "dockerfile"}
+
+// runConfigInit runs the configuration wizard, stores the API key in the credential store
+// and writes the other answers to the user configuration file, keeping its other keys
+func runConfigInit(f configInitFlags, out io.Writer) error {
+	path := f.config
+	if path == "" {
+		var err error
+		path, err = config.DefaultUserPath()
+		if err != nil {
+			return err
+		}
+	}
+	store, err := credentialStore()
+	if err != nil {
+		return err
+	}
+	osFs := afero.NewOsFs()
+	current, _, err := config.Load(config.Options{Fs: osFs, UserPath: path, Credentials: store})
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	result, err := tea.NewProgram(newConfigInitModel(current)).Run()
+	if err != nil {
+		return fmt.Errorf("error running program: %w", err)
+	}
+	m := result.(configInitModel)
+	if m.aborted {
+		fmt.Fprintln(out, lipgloss.NewStyle().Faint(true).Render("Configuration not changed."))
+		return nil
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	provider := m.values["provider"]
+	keyName := config.APIKeyName(provider)
+	if key, ok := m.values[keyName]; ok {
+		if err := store.Set(provider, key); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Stored the %s API key in the %s\n", provider, nameStyle.Render(store.String()))
+		// Don't leave a stale plaintext key behind, it would take precedence
+		if _, err := config.UnsetFileValue(osFs, path, "", keyName); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range configInitKeys {
+		value, ok := m.values[key]
+		if !ok {
+			continue
+		}
+		if err := config.SetFileValue(osFs, path, "", key, value); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "Configuration written to %s\n", nameStyle.Render(path))
+	return nil
+}
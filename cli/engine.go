@@ -2,7 +2,11 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/santiagomed/boil/core"
@@ -12,11 +16,61 @@ import (
 )
 
 type ExecutionRequest struct {
-	Request    *core.Request
+	// ID identifies the request in what is published about it. Submit assigns one if it's empty.
+	ID      string
+	Request *core.Request
+	// Augment, if set, adds the project to an existing repository
+	Augment *core.AugmentOptions
+	// NewFS, if set, creates the file system the project is generated in when a worker picks
+	// up the request, so that concurrent requests don't share the engine's file system
+	NewFS func() *fs.FileSystem
+	// Publisher, if set, receives the steps, errors and events of the request instead of the
+	// engine's publisher
+	Publisher  core.StepPublisher
 	ResultChan chan error
 	CreatedAt  time.Time
 }
 
+// RequestPublisher is implemented by publishers that receive the steps, errors and events of
+// several requests, with the ID of the request they belong to
+type RequestPublisher interface {
+	PublishRequestStep(requestID string, step core.StepType)
+	RequestError(requestID string, step core.StepType, err error)
+	PublishRequestEvent(requestID string, event core.Event)
+}
+
+// requestPublisher publishes what the pipeline of a request publishes, with the request's ID
+// if the publisher accepts it
+type requestPublisher struct {
+	id  string
+	pub core.StepPublisher
+}
+
+func (p *requestPublisher) PublishStep(step core.StepType) {
+	if pub, ok := p.pub.(RequestPublisher); ok {
+		pub.PublishRequestStep(p.id, step)
+		return
+	}
+	p.pub.PublishStep(step)
+}
+
+func (p *requestPublisher) Error(step core.StepType, err error) {
+	if pub, ok := p.pub.(RequestPublisher); ok {
+		pub.RequestError(p.id, step, err)
+		return
+	}
+	p.pub.Error(step, err)
+}
+
+func (p *requestPublisher) PublishEvent(event core.Event) {
+	switch pub := p.pub.(type) {
+	case RequestPublisher:
+		pub.PublishRequestEvent(p.id, event)
+	case core.EventPublisher:
+		pub.PublishEvent(event)
+	}
+}
+
 type Engine struct {
 	pub          core.StepPublisher
 	logger       logger.Logger
@@ -26,6 +80,12 @@ type Engine struct {
 	shutdownChan chan struct{}
 	fs           *fs.FileSystem
 	tellmURL     string
+	// limiters are the rate limiters of the API keys, shared by the workers
+	limiters *llm.RateLimiters
+	// newClient creates the client of a kind of step of a request
+	newClient func(r *core.Request, kind string, opts clientOptions) (llm.LlmClient, error)
+	// lastID is the number of the last request that was assigned an ID
+	lastID int64
 }
 
 func NewProjectEngine(pub core.StepPublisher, l logger.Logger, workers int, fs *fs.FileSystem, tellmURL string) (*Engine, error) {
@@ -40,6 +100,8 @@ func NewProjectEngine(pub core.StepPublisher, l logger.Logger, workers int, fs *
 		shutdownChan: make(chan struct{}),
 		fs:           fs,
 		tellmURL:     tellmURL,
+		limiters:     llm.NewRateLimiters(),
+		newClient:    newKindClient,
 	}, nil
 }
 
@@ -55,28 +117,7 @@ func (e *Engine) worker(ctx context.Context) {
 	for {
 		select {
 		case req := <-e.requests:
-			r := req.Request
-			llmCfg := llm.LlmConfig{
-				APIKey:    r.APIKey,
-				ModelName: r.ModelName,
-				BatchID:   llm.EnsureBatchID(r.ProjectName),
-				TellmURL:  e.tellmURL,
-			}
-			llmOpenAI, err := llm.NewOpenAIClient(&llmCfg, e.logger)
-			if err != nil {
-				req.ResultChan <- err
-				close(req.ResultChan)
-				continue
-			}
-
-			stepManager := core.NewDefaultStepManager(llmOpenAI, e.fs)
-			pipeline, err := core.NewPipeline(req.Request, stepManager, e.pub, e.logger)
-			if err != nil {
-				req.ResultChan <- err
-				close(req.ResultChan)
-				continue
-			}
-			err = pipeline.Execute(ctx)
+			err := e.execute(ctx, req)
 			req.ResultChan <- err
 			close(req.ResultChan)
 		case <-ctx.Done():
@@ -87,14 +128,156 @@ func (e *Engine) worker(ctx context.Context) {
 	}
 }
 
+// execute runs the pipeline of a request in its own file system, publishing to its own
+// publisher or to the engine's with the request's ID
+func (e *Engine) execute(ctx context.Context, req ExecutionRequest) error {
+	r := req.Request
+	if r.Usage == nil {
+		r.Usage = llm.NewUsageTracker()
+	}
+	pub := req.Publisher
+	if pub == nil {
+		pub = e.pub
+	}
+	pub = &requestPublisher{id: req.ID, pub: pub}
+
+	var pipeline *core.Pipeline
+	opts := clientOptions{
+		ctx:      ctx,
+		batchID:  llm.EnsureBatchID(r.ProjectName),
+		tellmURL: e.tellmURL,
+		logger:   e.logger,
+		limiters: e.limiters,
+		onWait: func(provider string, wait time.Duration) {
+			pipeline.PublishEvent(core.Event{
+				Type:    core.EventRateLimit,
+				Success: true,
+				Message: fmt.Sprintf("Waited %s for the %s rate limit", wait.Round(100*time.Millisecond), provider),
+			})
+		},
+	}
+	clients, err := core.NewStepClients(func(kind string) (llm.LlmClient, error) {
+		return e.newClient(r, kind, opts)
+	})
+	if err != nil {
+		return err
+	}
+	client := clients[core.GenerateFileContents]
+	projectFS := e.fs
+	if req.NewFS != nil {
+		projectFS = req.NewFS()
+	}
+	if projectFS == nil {
+		return errors.New("no file system to generate the project in")
+	}
+
+	var stepManager *core.DefaultStepManager
+	if req.Augment != nil {
+		// Generated files alone cannot be checked or built when adding to a repository, so
+		// there are no optional steps
+		stepManager = core.NewAugmentStepManagerWithClients(clients, client, projectFS, req.Augment)
+	} else {
+		stepManager = core.NewStepManagerWithClients(clients, client, projectFS)
+		after := core.GenerateFileContents
+		for _, step := range core.OptionalSteps(r) {
+			stepManager.InsertStepAfter(after, step)
+			after = step
+		}
+	}
+	pipeline, err = core.NewPipeline(r, stepManager, pub, e.logger)
+	if err != nil {
+		return err
+	}
+	return pipeline.Execute(ctx)
+}
+
+// clientOptions are the settings shared by the clients of a request
+type clientOptions struct {
+	// ctx, if set, cancels the completions of the clients
+	ctx      context.Context
+	batchID  string
+	tellmURL string
+	logger   logger.Logger
+	// limiters, if set, limit the completions of each API key
+	limiters *llm.RateLimiters
+	// onWait, if set, is called when a completion waited for the rate limit of a provider
+	onWait func(provider string, wait time.Duration)
+}
+
+// newKindClient creates the client of a kind of step of a request, which records the usage of
+// its completions. With fallback models, the client tries the kind's model and then each
+// fallback in order.
+func newKindClient(r *core.Request, kind string, opts clientOptions) (llm.LlmClient, error) {
+	redacted := logger.NewRedactingLogger(opts.logger, r.Secrets()...)
+	newClient := func(provider, model string) (llm.LlmClient, error) {
+		cfg := &llm.LlmConfig{
+			APIKey:      r.APIKeyFor(provider),
+			ModelName:   model,
+			BatchID:     opts.batchID,
+			TellmURL:    opts.tellmURL,
+			Conventions: r.Conventions.General(),
+			RateLimiter: opts.limiters.Get(provider, r.APIKeyFor(provider), r.RateLimit.RequestsPerMinute, r.RateLimit.TokensPerMinute),
+			Context:     opts.ctx,
+		}
+		if r.Usage != nil {
+			cfg.OnUsage = r.Usage.Recorder(kind)
+		}
+		cfg.OnRateLimitWait = func(wait time.Duration) {
+			redacted.Info(fmt.Sprintf("Waited %v for the %s rate limit", wait, provider))
+			if opts.onWait != nil {
+				opts.onWait(provider, wait)
+			}
+		}
+		return llm.NewClient(provider, cfg, redacted)
+	}
+	if len(r.Fallbacks) == 0 {
+		return newClient(r.Provider, r.Model(kind))
+	}
+
+	refs := append([]string{r.Provider + "/" + r.Model(kind)}, r.Fallbacks...)
+	targets := make([]llm.FallbackTarget, 0, len(refs))
+	for _, ref := range refs {
+		provider, model, err := llm.ParseModelRef(ref, r.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback model: %w", err)
+		}
+		client, err := newClient(provider, model)
+		if err != nil {
+			return nil, fmt.Errorf("error creating client for %s/%s: %w", provider, model, err)
+		}
+		targets = append(targets, llm.FallbackTarget{Provider: provider, Model: model, Client: client})
+	}
+	client, err := llm.NewFallbackClient(targets, redacted)
+	if err != nil {
+		return nil, err
+	}
+	client.OnServed = func(served llm.Served) {
+		if len(served.Failures) > 0 && r.Usage != nil {
+			r.Usage.RecordFallback(kind, served.Model)
+		}
+	}
+	return client, nil
+}
+
 func (e *Engine) AddRequest(request *core.Request) chan error {
-	resultChan := make(chan error, 1)
-	e.requests <- ExecutionRequest{
-		Request:    request,
-		ResultChan: resultChan,
-		CreatedAt:  time.Now(),
+	return e.AddAugmentRequest(request, nil)
+}
+
+// AddAugmentRequest queues a request that adds a project to an existing repository
+func (e *Engine) AddAugmentRequest(request *core.Request, augment *core.AugmentOptions) chan error {
+	return e.Submit(ExecutionRequest{Request: request, Augment: augment})
+}
+
+// Submit queues an execution request and returns the channel its result is sent to. It
+// assigns the request an ID if it has none.
+func (e *Engine) Submit(req ExecutionRequest) chan error {
+	if req.ID == "" {
+		req.ID = strconv.FormatInt(atomic.AddInt64(&e.lastID, 1), 10)
 	}
-	return resultChan
+	req.ResultChan = make(chan error, 1)
+	req.CreatedAt = time.Now()
+	e.requests <- req
+	return req.ResultChan
 }
 
 func (e *Engine) Shutdown(timeout time.Duration) {
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// projectLLM answers every prompt of a pipeline with a one-file project whose content is
+// the name of its request's project
+type projectLLM struct {
+	project string
+}
+
+func (c *projectLLM) GetCompletion(prompt, responseType string) (string, error) {
+	switch {
+	case strings.HasPrefix(prompt, "Generate file operations"):
+		return `{"operations": [{"operation": "CREATE_FILE", "path": "main.txt"}]}`, nil
+	case strings.HasPrefix(prompt, "Given the following file tree"):
+		return `{"files": ["main.txt"]}`, nil
+	}
+	return c.project, nil
+}
+
+// recordingPublisher records what is published about each request
+type recordingPublisher struct {
+	core.DefaultStepPublisher
+	mu    sync.Mutex
+	steps map[string][]core.StepType
+}
+
+func (p *recordingPublisher) PublishRequestStep(requestID string, step core.StepType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps[requestID] = append(p.steps[requestID], step)
+}
+
+func (p *recordingPublisher) RequestError(requestID string, step core.StepType, err error) {}
+
+func (p *recordingPublisher) PublishRequestEvent(requestID string, event core.Event) {}
+
+func TestEngine_ConcurrentRequests(t *testing.T) {
+	pub := &recordingPublisher{steps: make(map[string][]core.StepType)}
+	engine, err := NewProjectEngine(pub, nil, 4, nil, "")
+	require.NoError(t, err)
+	engine.newClient = func(r *core.Request, kind string, opts clientOptions) (llm.LlmClient, error) {
+		return &projectLLM{project: r.ProjectName}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine.Start(ctx)
+	defer engine.Shutdown(time.Second)
+
+	const requests = 8
+	filesystems := make([]*fs.FileSystem, requests)
+	results := make([]chan error, requests)
+	for i := 0; i < requests; i++ {
+		i := i
+		results[i] = engine.Submit(ExecutionRequest{
+			ID:      fmt.Sprintf("request-%d", i),
+			Request: &core.Request{ProjectName: fmt.Sprintf("project-%d", i), ProjectDescription: "description"},
+			NewFS: func() *fs.FileSystem {
+				filesystems[i] = fs.NewMemoryFileSystem()
+				return filesystems[i]
+			},
+		})
+	}
+	for i, result := range results {
+		require.NoError(t, <-result, "request %d", i)
+	}
+
+	for i, projectFS := range filesystems {
+		content, err := afero.ReadFile(projectFS.Fs, "main.txt")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("project-%d", i), string(content))
+
+		steps := pub.steps[fmt.Sprintf("request-%d", i)]
+		require.NotEmpty(t, steps)
+		assert.Equal(t, core.GenerateProjectDetails, steps[0])
+		assert.Equal(t, core.Done, steps[len(steps)-1])
+	}
+	assert.Len(t, pub.steps, requests)
+}
+
+func TestEngine_SubmitAssignsIDs(t *testing.T) {
+	engine, err := NewProjectEngine(&core.DefaultStepPublisher{}, nil, 1, fs.NewMemoryFileSystem(), "")
+	require.NoError(t, err)
+
+	first := ExecutionRequest{Request: &core.Request{}}
+	engine.Submit(first)
+	engine.Submit(ExecutionRequest{ID: "named", Request: &core.Request{}})
+	engine.Submit(first)
+
+	assert.Equal(t, "1", (<-engine.requests).ID)
+	assert.Equal(t, "named", (<-engine.requests).ID)
+	assert.Equal(t, "2", (<-engine.requests).ID)
+}
@@ -15,9 +15,12 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/list"
 	"github.com/santiagomed/boil/config"
+	"github.com/santiagomed/boil/conventions"
 	"github.com/santiagomed/boil/core"
 	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
 	"github.com/santiagomed/boil/logger"
+	"github.com/santiagomed/boil/templates"
 	"github.com/santiagomed/boil/utils"
 	"github.com/spf13/afero"
 )
@@ -29,12 +32,26 @@ const (
 	Initializing
 	Processing
 	Questions
+	Reviewing
 	Finished
 )
 
 type genFlags struct {
-	name   string
-	config string
+	name    string
+	config  string
+	profile string
+	// template is the name of a saved template merged into the request before the flags
+	template string
+	output   string
+	onExists fs.ExistsPolicy
+	// archive is the path of an archive to write instead of a directory, or "-" for stdout
+	archive       string
+	archiveFormat fs.ArchiveFormat
+	verify        bool
+	verifyCommand string
+	repair        bool
+	// repairRounds overrides repair_rounds from the config if set
+	repairRounds int
 }
 
 type generateCmdModel struct {
@@ -51,9 +68,108 @@ type generateCmdModel struct {
 	publisher       *CliStepPublisher
 	logger          logger.Logger
 	fs              *fs.FileSystem
+	onExists        fs.ExistsPolicy
+	archive         string
+	archiveFormat   fs.ArchiveFormat
+	// augment and into are set when adding a project to an existing repository
+	augment *core.AugmentOptions
+	into    string
+	review  *reviewState
+	width   int
+	height  int
 }
 
 func newGenerateModel(f genFlags) (generateCmdModel, error) {
+	req, origins, err := loadRequest(f.config, f.profile)
+	if err != nil {
+		return generateCmdModel{}, err
+	}
+
+	var tmpl *templates.Template
+	if f.template != "" {
+		tmpl, err = loadTemplate(f.template)
+		if err != nil {
+			return generateCmdModel{}, err
+		}
+		tmpl.Apply(req)
+	}
+
+	if err := applyGenFlags(req, origins, f); err != nil {
+		return generateCmdModel{}, err
+	}
+
+	// Refuse early, before any LLM calls, if the project could not be written
+	if err := checkGenDestination(req, f); err != nil {
+		return generateCmdModel{}, err
+	}
+
+	m, err := newModel(req)
+	if err != nil {
+		return generateCmdModel{}, err
+	}
+	m.onExists = f.onExists
+	m.archive = f.archive
+	m.archiveFormat = f.archiveFormat
+
+	// Skip the prompts the template answers
+	if tmpl != nil && tmpl.Description != "" {
+		m.state = Questions
+		if tmpl.Components != nil {
+			m.state = Initializing
+		}
+	}
+	return m, nil
+}
+
+// loadRequest loads the request defaults from the configuration layers and the credential
+// store, with the given config file in place of the user configuration file and the given
+// profile if set, along with the conventions they name or the ones in the current directory
+func loadRequest(configPath, profile string) (*core.Request, config.Origins, error) {
+	store, err := credentialStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	req, origins, err := config.Load(config.Options{ConfigPath: configPath, Credentials: store, Profile: profile})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading config: %w", err)
+	}
+
+	req.Conventions, err = conventions.Find(afero.NewOsFs(), req.ConventionsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, origins, nil
+}
+
+// applyGenFlags overrides the configuration with the flags that are set
+func applyGenFlags(req *core.Request, origins config.Origins, f genFlags) error {
+	type flagValue struct {
+		flag  string
+		key   string
+		value interface{}
+		set   bool
+	}
+	values := []flagValue{
+		{"--name", "project_name", f.name, f.name != ""},
+		{"--output", "output_dir", f.output, f.output != ""},
+		{"--verify", "verify", true, f.verify || f.verifyCommand != ""},
+		{"--verify-command", "verify_command", f.verifyCommand, f.verifyCommand != ""},
+		{"--repair", "repair", true, f.repair || f.repairRounds > 0},
+		{"--repair-rounds", "repair_rounds", f.repairRounds, f.repairRounds > 0},
+	}
+	for _, v := range values {
+		if !v.set {
+			continue
+		}
+		if err := config.Set(req, origins, v.key, v.value, config.Origin{Layer: config.LayerFlag, Name: v.flag}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newModel creates the generation model for a request and starts its engine
+func newModel(req *core.Request) (generateCmdModel, error) {
 	ti := textinput.New()
 	ti.Placeholder = "Describe your project..."
 	ti.Focus()
@@ -67,31 +183,9 @@ func newGenerateModel(f genFlags) (generateCmdModel, error) {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("202"))
 
-	var req *core.Request
-	var err error
-
-	if f.config != "" || f.config == "" {
-		configPath := f.config
-		if configPath == "" {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return generateCmdModel{}, fmt.Errorf("error getting user home directory: %w", err)
-			}
-			configPath = filepath.Join(home, ".boil", "config.yaml")
-		}
-
-		req, err = config.LoadConfig(configPath)
-		if err != nil {
-			return generateCmdModel{}, fmt.Errorf("error loading config: %w", err)
-		}
-	} else {
-		req = core.DefaultRequest()
-	}
-
-	if f.name != "" {
-		req.ProjectName = f.name
+	if req.Usage == nil {
+		req.Usage = llm.NewUsageTracker()
 	}
-
 	fs := fs.NewMemoryFileSystem()
 	publisher := NewCliStepPublisher(logger)
 	engine, err := NewProjectEngine(publisher, logger, 1, fs, "http://localhost:8000")
@@ -113,6 +207,8 @@ func newGenerateModel(f genFlags) (generateCmdModel, error) {
 		engineCancel:    cancel,
 		publisher:       publisher,
 		currentQuestion: 0,
+		width:           80,
+		height:          24,
 	}
 	engine.Start(ctx)
 	return m, nil
@@ -141,8 +237,16 @@ func (m generateCmdModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			return m, cmd
 		}
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		if m.review != nil {
+			m.review.viewport.Width = m.width
+			m.review.viewport.Height = m.reviewHeight()
+		}
 	case core.StepType:
 		return m.handleStep(msg)
+	case core.Event:
+		return m.handleEvent(msg)
 	case error:
 		return m, tea.Sequence(tea.Printf("Error: %s", msg), tea.Quit)
 	default:
@@ -167,18 +271,26 @@ func (m generateCmdModel) View() string {
 	case Initializing:
 		return fmt.Sprintf("%s Initializing", m.spinner.View())
 	case Processing:
-		steps := []struct {
+		type stepLabel struct {
 			present string
 			past    string
-		}{
-			{"Generating project details.", "Generated project details."},
-			{"Generating file tree.", "Generated file tree."},
-			{"Generating file operations.", "Generated file operations."},
-			{"Executing file operations.", "Executed file operations."},
-			{"Determining file order.", "Determined file order."},
-			{"Generating file contents.", "Generated file contents."},
-			{"Creating optional components.", "Created optional components."},
-			{"Done.", "Done."},
+		}
+		labels := map[core.StepType]stepLabel{
+			core.GenerateProjectDetails:   {"Generating project details.", "Generated project details."},
+			core.GenerateFileTree:         {"Generating file tree.", "Generated file tree."},
+			core.GenerateFileOperations:   {"Generating file operations.", "Generated file operations."},
+			core.ExecuteFileOperations:    {"Executing file operations.", "Executed file operations."},
+			core.DetermineFileOrder:       {"Determining file order.", "Determined file order."},
+			core.GenerateFileContents:     {"Generating file contents.", "Generated file contents."},
+			core.CheckProject:             {"Checking project.", "Checked project."},
+			core.VerifyProject:            {"Verifying project.", "Verified project."},
+			core.RepairProject:            {"Repairing project.", "Repaired project."},
+			core.CreateOptionalComponents: {"Creating optional components.", "Created optional components."},
+			core.Done:                     {"Done.", "Done."},
+		}
+		var steps []stepLabel
+		for _, step := range m.pipelineSteps() {
+			steps = append(steps, labels[step])
 		}
 
 		enumerator := func(l list.Items, i int) string {
@@ -219,6 +331,8 @@ func (m generateCmdModel) View() string {
 		}
 		output.WriteString("\n(Enter 'b' to go back, or 'esc' to quit)")
 		return output.String()
+	case Reviewing:
+		return m.reviewView()
 	case Finished:
 		return "Project generated successfully!"
 	default:
@@ -227,6 +341,22 @@ func (m generateCmdModel) View() string {
 	}
 }
 
+// pipelineSteps returns the steps the engine runs for the request, in order
+func (m *generateCmdModel) pipelineSteps() []core.StepType {
+	steps := []core.StepType{
+		core.GenerateProjectDetails,
+		core.GenerateFileTree,
+		core.GenerateFileOperations,
+		core.ExecuteFileOperations,
+		core.DetermineFileOrder,
+		core.GenerateFileContents,
+	}
+	if m.augment == nil {
+		steps = append(steps, core.OptionalSteps(m.request)...)
+	}
+	return append(steps, core.CreateOptionalComponents, core.Done)
+}
+
 func (m *generateCmdModel) Shutdown() {
 	m.engineCancel()                   // Cancel the engine context
 	m.engine.Shutdown(5 * time.Second) // Give 5 seconds for graceful shutdown
@@ -239,6 +369,8 @@ func (m *generateCmdModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleInputState(msg)
 	case Questions:
 		return m.handleQuestionsState(msg)
+	case Reviewing:
+		return m.handleReviewState(msg)
 	default:
 		return m.handleQuit(msg)
 	}
@@ -332,6 +464,8 @@ func (m *generateCmdModel) listenForNextStep() tea.Msg {
 	select {
 	case step := <-m.publisher.stepChan:
 		return step
+	case event := <-m.publisher.eventChan:
+		return event
 	case err := <-m.publisher.errorChan:
 		m.logger.Error(fmt.Sprintf("Error received during project generation: %v", err))
 		return err
@@ -339,7 +473,7 @@ func (m *generateCmdModel) listenForNextStep() tea.Msg {
 }
 
 func (m *generateCmdModel) handleProjectGeneration() tea.Cmd {
-	resultChan := m.engine.AddRequest(m.request)
+	resultChan := m.engine.AddAugmentRequest(m.request, m.augment)
 	listenForError := func() tea.Msg {
 		select {
 		case err := <-resultChan:
@@ -364,24 +498,156 @@ func (m *generateCmdModel) handleStep(step core.StepType) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(m.spinner.Tick, m.listenForNextStep)
 }
 
+// handleEvent prints events published during a step and keeps listening for steps
+func (m *generateCmdModel) handleEvent(event core.Event) (tea.Model, tea.Cmd) {
+	m.logger.Debug(fmt.Sprintf("Received %s event for step: %v", event.Type, event.Step))
+	return m, tea.Batch(tea.Printf("%s %s", eventMark(event), event.Message), m.listenForNextStep)
+}
+
+// eventMark returns the mark an event is printed with
+func eventMark(event core.Event) string {
+	switch {
+	case event.Type == core.EventRepair:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("↻")
+	case event.Type == core.EventCheck:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("!")
+	case event.Type == core.EventRateLimit:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("⧗")
+	case !event.Success:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render("✗")
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("✓")
+}
+
 func (m *generateCmdModel) handleProjectFinalization() (tea.Model, tea.Cmd) {
 	m.logger.Info("Finalizing project.")
 	m.state = Finished
-	projectName := utils.FormatProjectName(m.request.ProjectName)
+	if m.augment != nil {
+		return m.handleAugmentFinalization()
+	}
+	if m.archive != "" {
+		return m.handleArchiveFinalization()
+	}
+	projectName := projectDir(m.request)
+	if m.onExists == fs.ExistsReview {
+		if exists, _ := afero.DirExists(afero.NewOsFs(), projectName); exists {
+			return m.startReview(projectName)
+		}
+	}
 
-	err := m.engine.fs.CopyDir(afero.NewOsFs(), ".", projectName)
+	report, err := m.engine.fs.WriteProject(afero.NewOsFs(), ".", projectName, m.onExists)
 	if err != nil {
 		m.logger.Error(fmt.Sprintf("Failed to copy project to disk: %v", err))
-		return m, tea.Quit
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
 	}
 
 	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
 	outProjectName := nameStyle.Render(projectName)
 	finalMsg := fmt.Sprintf("Project generated in directory: %s", outProjectName)
+	finalMsg += formatWriteReport(report)
+	finalMsg += formatUsage(m.request.Usage.Usage())
 
 	return m, tea.Printf("%s", finalMsg)
 }
 
+// handleArchiveFinalization writes the generated project as an archive instead of a directory
+func (m *generateCmdModel) handleArchiveFinalization() (tea.Model, tea.Cmd) {
+	if m.archive == "-" {
+		if err := m.engine.fs.WriteArchive(os.Stdout, m.archiveFormat); err != nil {
+			m.logger.Error(fmt.Sprintf("Failed to write archive to stdout: %v", err))
+			return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+		}
+		return m, tea.Printf("Project archive written to stdout%s", formatUsage(m.request.Usage.Usage()))
+	}
+
+	if err := m.engine.fs.WriteArchiveFile(afero.NewOsFs(), m.archive, m.archiveFormat); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to write archive: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	return m, tea.Printf("Project archive written to: %s%s", nameStyle.Render(m.archive), formatUsage(m.request.Usage.Usage()))
+}
+
+// checkGenDestination verifies that the project of a request can be written where the flags
+// ask for, either as a directory or as an archive
+func checkGenDestination(req *core.Request, f genFlags) error {
+	osFs := afero.NewOsFs()
+	if f.archive == "-" {
+		return nil
+	}
+	if f.archive != "" {
+		if isDir, _ := afero.IsDir(osFs, f.archive); isDir {
+			return fmt.Errorf("archive path %s is a directory", f.archive)
+		}
+		return fs.EnsureWritableDir(osFs, filepath.Dir(f.archive))
+	}
+
+	if err := fs.EnsureWritableDir(osFs, outputDir(req.OutputDir)); err != nil {
+		return err
+	}
+	if err := fs.CheckOutputDir(osFs, projectDir(req), f.onExists); err != nil {
+		return fmt.Errorf("%w (use --on-exists to overwrite, merge, back up or review it)", err)
+	}
+	return nil
+}
+
+// outputDir returns the directory generated projects are written to, defaulting to the current directory
+func outputDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// projectDir returns the directory the project of a request is written to
+func projectDir(r *core.Request) string {
+	return filepath.Join(outputDir(r.OutputDir), utils.FormatProjectName(r.ProjectName))
+}
+
+// formatWriteReport describes overwritten files and backups of a project write
+func formatWriteReport(report *fs.WriteReport) string {
+	var output strings.Builder
+	faint := lipgloss.NewStyle().Faint(true)
+	if report.BackupPath != "" {
+		output.WriteString(fmt.Sprintf("\nPrevious directory backed up to: %s", report.BackupPath))
+	}
+	if len(report.Conflicts) > 0 {
+		output.WriteString(fmt.Sprintf("\nOverwrote %d existing file(s):", len(report.Conflicts)))
+		for _, c := range report.Conflicts {
+			output.WriteString("\n" + faint.Render("  - "+c))
+		}
+	}
+	return output.String()
+}
+
+// formatUsage describes the tokens used by a run and their estimated cost, by kind of step
+func formatUsage(usage []llm.Usage) string {
+	if len(usage) == 0 {
+		return ""
+	}
+	var output strings.Builder
+	faint := lipgloss.NewStyle().Faint(true)
+	total := llm.TotalUsage(usage)
+	output.WriteString(fmt.Sprintf("\nUsed %d input and %d output tokens in %d completions%s", total.InputTokens, total.OutputTokens, total.Calls, formatCost(total.Cost)))
+	for _, u := range usage {
+		line := fmt.Sprintf("  - %s (%s): %d input, %d output tokens%s", u.Kind, u.Model, u.InputTokens, u.OutputTokens, formatCost(u.Cost))
+		if u.Fallbacks > 0 {
+			line += fmt.Sprintf(", %d %s served as a fallback", u.Fallbacks, plural(u.Fallbacks, "completion", "completions"))
+		}
+		output.WriteString("\n" + faint.Render(line))
+	}
+	return output.String()
+}
+
+// formatCost formats an estimated cost in US dollars, or nothing if it is unknown
+func formatCost(cost float64) string {
+	if cost == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (~$%.4f)", cost)
+}
+
 // handleQuit handles the quit state of the application on key press.
 func (m *generateCmdModel) handleQuit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyEsc {
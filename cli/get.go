@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/config"
 )
 
 type progressMsg float64
@@ -23,7 +24,9 @@ type progressErrMsg struct{ err error }
 type downloadCompleteMsg struct{}
 
 type getFlags struct {
-	token string
+	token  string
+	output string
+	config string
 }
 
 const (
@@ -35,12 +38,13 @@ type getCmdModel struct {
 	pw        *progressWriter
 	progress  progress.Model
 	path      string
+	outputDir string
 	textinput textinput.Model
 	state     int
 	err       error
 }
 
-func newGetCmdModel(pw *progressWriter, path string) getCmdModel {
+func newGetCmdModel(pw *progressWriter, path, outputDir string) getCmdModel {
 	textinput := textinput.New()
 	textinput.Placeholder = "my-boil-project"
 	textinput.Focus()
@@ -52,6 +56,7 @@ func newGetCmdModel(pw *progressWriter, path string) getCmdModel {
 		progress:  progress.New(progress.WithGradient("#FFBA08", "#F48C06")),
 		textinput: textinput,
 		path:      path,
+		outputDir: outputDir,
 		state:     downloading,
 	}
 }
@@ -130,25 +135,32 @@ func finalPause() tea.Cmd {
 
 func (m getCmdModel) handleSaveProject(name string) (tea.Model, tea.Cmd) {
 	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFBA08"))
-	cwd, err := os.Getwd()
-	if err != nil {
-		fmt.Println(errorStyle.Render(fmt.Sprintf("Error getting current working directory: %v", err)))
-		m.err = err
-		return m, tea.Quit
-	}
-	destDir := filepath.Join(cwd, name)
+	destDir := filepath.Join(m.outputDir, name)
 	if err := unzip(m.path, destDir); err != nil {
 		fmt.Println(errorStyle.Render(fmt.Sprintf("Error unzipping file: %v", err)))
 		m.err = err
 		return m, tea.Quit
 	}
 	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
-	successProject := successStyle.Render(name)
+	successProject := successStyle.Render(destDir)
 	check := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("✓")
 	fmt.Printf("%s Project saved to directory %s\n", check, successProject)
 	return m, tea.Quit
 }
 
+// resolveGetOutputDir returns the directory downloaded projects are saved to: the --output
+// flag if set, otherwise output_dir from the configuration, otherwise the current directory
+func resolveGetOutputDir(f getFlags) (string, error) {
+	if f.output != "" {
+		return f.output, nil
+	}
+	req, _, err := config.Load(config.Options{ConfigPath: f.config})
+	if err != nil {
+		return "", fmt.Errorf("error loading config: %w", err)
+	}
+	return outputDir(req.OutputDir), nil
+}
+
 func downloadFile(url, token string) (*http.Response, error) {
 	// Create the request
 	req, err := http.NewRequest("GET", url, nil)
@@ -245,13 +257,19 @@ func unzip(src, dest string) error {
 			return err
 		}
 
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+		mode := f.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+
+		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 		if err != nil {
 			return err
 		}
 
 		rc, err := f.Open()
 		if err != nil {
+			outFile.Close()
 			return err
 		}
 
@@ -263,6 +281,10 @@ func unzip(src, dest string) error {
 		if err != nil {
 			return err
 		}
+
+		if err = os.Chmod(fpath, mode); err != nil {
+			return err
+		}
 	}
 	return nil
 }
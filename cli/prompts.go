@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+)
+
+type promptsDumpFlags struct {
+	force bool
+}
+
+// loadPrompts overrides the default prompts with the user's prompt templates
+func loadPrompts() error {
+	dir, err := llm.DefaultPromptsDir()
+	if err != nil {
+		return err
+	}
+	return llm.LoadPrompts(afero.NewOsFs(), dir)
+}
+
+// runPromptsDump writes the default prompt templates to dir, or to the user's prompts
+// directory if dir is empty, so they can be edited
+func runPromptsDump(f promptsDumpFlags, dir string, out io.Writer) error {
+	if dir == "" {
+		var err error
+		dir, err = llm.DefaultPromptsDir()
+		if err != nil {
+			return err
+		}
+	}
+
+	osFs := afero.NewOsFs()
+	defaults := llm.DefaultPrompts()
+	names := llm.PromptNames()
+	if !f.force {
+		for _, name := range names {
+			path := filepath.Join(dir, name+llm.PromptExt)
+			if exists, _ := afero.Exists(osFs, path); exists {
+				return fmt.Errorf("%s already exists (use --force to overwrite it)", path)
+			}
+		}
+	}
+
+	if err := osFs.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating prompts directory: %w", err)
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name+llm.PromptExt)
+		if err := afero.WriteFile(osFs, path, []byte(defaults[name]), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	faint := lipgloss.NewStyle().Faint(true)
+	fmt.Fprintf(out, "Wrote %d prompt templates to %s\n", len(names), nameStyle.Render(dir))
+	fmt.Fprintln(out, faint.Render("Templates in ~/.boil/prompts override the defaults. Delete the ones you don't change to keep getting updated defaults."))
+	return nil
+}
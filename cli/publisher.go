@@ -10,6 +10,7 @@ import (
 type CliStepPublisher struct {
 	stepChan  chan core.StepType
 	errorChan chan error
+	eventChan chan core.Event
 	logger    logger.Logger
 }
 
@@ -17,6 +18,7 @@ func NewCliStepPublisher(logger logger.Logger) *CliStepPublisher {
 	return &CliStepPublisher{
 		stepChan:  make(chan core.StepType, 100), // Buffer size of 100
 		errorChan: make(chan error, 10),          // Buffer size of 10
+		eventChan: make(chan core.Event, 100),
 		logger:    logger,
 	}
 }
@@ -38,3 +40,12 @@ func (p *CliStepPublisher) Error(step core.StepType, err error) {
 		p.logger.Warn(fmt.Sprintf("Failed to publish error for step: %v. Channel full.", step))
 	}
 }
+
+func (p *CliStepPublisher) PublishEvent(event core.Event) {
+	select {
+	case p.eventChan <- event:
+		p.logger.Debug(fmt.Sprintf("Successfully published %s event for step: %v", event.Type, event.Step))
+	default:
+		p.logger.Warn(fmt.Sprintf("Failed to publish %s event for step: %v. Channel full.", event.Type, event.Step))
+	}
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+)
+
+type regenFlags struct {
+	hint    string
+	project string
+	config  string
+	profile string
+	yes     bool
+}
+
+// runRegen regenerates a single file of an existing project, shows the diff and writes
+// the new content once confirmed
+func runRegen(f regenFlags, file string, in io.Reader, out io.Writer) error {
+	root, err := findProjectRoot(f.project, file)
+	if err != nil {
+		return err
+	}
+	relPath, err := projectRelPath(root, file)
+	if err != nil {
+		return err
+	}
+
+	req, _, err := loadRequest(f.config, f.profile)
+	if err != nil {
+		return err
+	}
+
+	InitLogger()
+	client, err := newKindClient(req, core.ModelCode, clientOptions{
+		batchID:  llm.EnsureBatchID(""),
+		tellmURL: "http://localhost:8000",
+		logger:   GetLogger(),
+	})
+	if err != nil {
+		return err
+	}
+
+	project, err := fs.LoadDir(afero.NewOsFs(), root)
+	if err != nil {
+		return err
+	}
+	if !project.Exists(relPath) {
+		return fmt.Errorf("%s is not part of the project in %s (it may be ignored by .gitignore)", file, root)
+	}
+
+	fmt.Fprintf(out, "Regenerating %s...\n", relPath)
+	content, err := core.RegenerateFile(client, project, relPath, f.hint, req.Conventions)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, relPath)
+	current, err := os.ReadFile(target)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", target, err)
+	}
+	if string(current) == content {
+		fmt.Fprintln(out, "No changes.")
+		return nil
+	}
+
+	diff := fs.UnifiedDiff(filepath.ToSlash(relPath), current, []byte(content), true)
+	fmt.Fprintln(out, renderDiff(fs.FileDiff{Unified: diff}))
+
+	if !f.yes && !confirm(in, out, "Apply changes?") {
+		fmt.Fprintln(out, "Changes discarded.")
+		return nil
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("error accessing %s: %w", target, err)
+	}
+	if err := os.WriteFile(target, []byte(content), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing %s: %w", target, err)
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	fmt.Fprintf(out, "Regenerated %s\n", nameStyle.Render(target))
+	return nil
+}
+
+// findProjectRoot returns the project directory: the given one if set, otherwise the
+// closest ancestor of file with saved project metadata, otherwise the current directory
+func findProjectRoot(project, file string) (string, error) {
+	if project != "" {
+		return project, nil
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %w", file, err)
+	}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(core.MetadataPath))); err == nil {
+			return dir, nil
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	return ".", nil
+}
+
+// projectRelPath returns the path of file relative to the project root
+func projectRelPath(root, file string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %w", root, err)
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %w", file, err)
+	}
+	relPath, err := filepath.Rel(absRoot, absFile)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%s is outside of the project directory %s", file, root)
+	}
+	return relPath, nil
+}
+
+// confirm asks a yes/no question and reports whether the answer was yes
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s (y/n): ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
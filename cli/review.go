@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/fs"
+	"github.com/spf13/afero"
+)
+
+var (
+	diffAddStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	diffHunkStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	diffHeaderStyle = lipgloss.NewStyle().Bold(true)
+)
+
+// reviewState holds the progress of reviewing the changes to an existing project directory
+type reviewState struct {
+	diffs    []fs.FileDiff
+	index    int
+	accepted []string
+	viewport viewport.Model
+}
+
+// startReview compares the generated project with the existing project directory and
+// starts reviewing the changes file by file. It writes nothing if there are no changes.
+func (m *generateCmdModel) startReview(projectDir string) (tea.Model, tea.Cmd) {
+	diffs, err := m.engine.fs.Diff(afero.NewOsFs(), ".", projectDir)
+	if err != nil {
+		m.state = Finished
+		m.logger.Error(fmt.Sprintf("Failed to compare project with %s: %v", projectDir, err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+	if len(diffs) == 0 {
+		m.state = Finished
+		return m, tea.Printf("No changes to %s", projectDir)
+	}
+
+	m.state = Reviewing
+	m.review = &reviewState{
+		diffs:    diffs,
+		viewport: viewport.New(m.width, m.reviewHeight()),
+	}
+	m.review.viewport.SetContent(renderDiff(diffs[0]))
+	return m, nil
+}
+
+// reviewHeight returns the height of the diff viewport, leaving room for the header and help
+func (m *generateCmdModel) reviewHeight() int {
+	if m.height-4 < 5 {
+		return 5
+	}
+	return m.height - 4
+}
+
+// handleReviewState handles key presses while reviewing changes
+func (m *generateCmdModel) handleReviewState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.review
+	switch msg.String() {
+	case "y":
+		r.accepted = append(r.accepted, r.diffs[r.index].Path)
+		return m.nextReview()
+	case "n":
+		return m.nextReview()
+	case "a":
+		for _, d := range r.diffs[r.index:] {
+			r.accepted = append(r.accepted, d.Path)
+		}
+		return m.finishReview()
+	case "q":
+		return m.finishReview()
+	case "ctrl+c", "esc":
+		return m.handleQuit(msg)
+	}
+
+	var cmd tea.Cmd
+	r.viewport, cmd = r.viewport.Update(msg)
+	return m, cmd
+}
+
+// nextReview moves on to the next changed file, or writes the accepted files after the last one
+func (m *generateCmdModel) nextReview() (tea.Model, tea.Cmd) {
+	r := m.review
+	r.index++
+	if r.index >= len(r.diffs) {
+		return m.finishReview()
+	}
+	r.viewport.SetContent(renderDiff(r.diffs[r.index]))
+	r.viewport.GotoTop()
+	return m, func() tea.Msg { return nil }
+}
+
+// finishReview merges the accepted files into the project directory
+func (m *generateCmdModel) finishReview() (tea.Model, tea.Cmd) {
+	m.state = Finished
+	projectName := projectDir(m.request)
+	accepted := m.review.accepted
+	if len(accepted) == 0 {
+		return m, tea.Printf("No changes written to %s", projectName)
+	}
+
+	subset, err := m.engine.fs.Subset(accepted)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to select accepted files: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+	report, err := subset.WriteProject(afero.NewOsFs(), ".", projectName, fs.ExistsReview)
+	if err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to copy project to disk: %v", err))
+		return m, tea.Sequence(tea.Printf("Error: %s", err), tea.Quit)
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	finalMsg := fmt.Sprintf("Wrote %d of %d changed file(s) to: %s",
+		len(accepted), len(m.review.diffs), nameStyle.Render(projectName))
+	finalMsg += formatWriteReport(report)
+	return m, tea.Printf("%s", finalMsg)
+}
+
+// reviewView renders the diff of the file being reviewed
+func (m *generateCmdModel) reviewView() string {
+	r := m.review
+	d := r.diffs[r.index]
+	header := fmt.Sprintf("[%d/%d] %s (%s)", r.index+1, len(r.diffs), d.Path, d.Change)
+	if d.Change == fs.ChangeModified && d.OldMode != d.NewMode {
+		header += fmt.Sprintf(" mode %04o → %04o", d.OldMode, d.NewMode)
+	}
+	help := helpStyle("y: accept • n: reject • a: accept all remaining • q: reject all remaining • ↑/↓: scroll")
+	return diffHeaderStyle.Render(header) + "\n" + r.viewport.View() + "\n" + help
+}
+
+// renderDiff colors a unified diff for display
+func renderDiff(d fs.FileDiff) string {
+	if d.Unified == "" {
+		return helpStyle("Only the file mode changed.")
+	}
+	lines := strings.Split(strings.TrimRight(d.Unified, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = diffHeaderStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffRemoveStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,598 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/santiagomed/boil/config"
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/santiagomed/boil/logger"
+	"github.com/santiagomed/boil/utils"
+	"github.com/spf13/afero"
+)
+
+// jobLayer is the origin of the configuration values of a job submitted to the server
+const jobLayer = "job"
+
+type serveFlags struct {
+	addr    string
+	config  string
+	profile string
+	// concurrency is the number of jobs run at the same time
+	concurrency int
+	jobsDir     string
+	token       string
+}
+
+// jobKeys are the configuration keys a job can set. The others, such as API keys, rate
+// limits and the verify command, are left to the server's configuration.
+var jobKeys = map[string]bool{
+	"project_name":        true,
+	"project_description": true,
+	"instructions":        true,
+	"file_tree":           true,
+	"git_repo":            true,
+	"git_ignore":          true,
+	"readme":              true,
+	"dockerfile":          true,
+	"static_checks":       true,
+	"provider":            true,
+	"model_name":          true,
+	"models.planning":     true,
+	"models.code":         true,
+	"models.docs":         true,
+	"fallbacks":           true,
+}
+
+// jobStatus is the state of a job
+type jobStatus string
+
+const (
+	jobQueued    jobStatus = "queued"
+	jobRunning   jobStatus = "running"
+	jobSucceeded jobStatus = "succeeded"
+	jobFailed    jobStatus = "failed"
+)
+
+// finished reports whether a job in the status is done running
+func (s jobStatus) finished() bool {
+	return s == jobSucceeded || s == jobFailed
+}
+
+// stepNames are the names of the steps in job events
+var stepNames = map[core.StepType]string{
+	core.GenerateProjectDetails:   "project_details",
+	core.GenerateFileTree:         "file_tree",
+	core.GenerateFileOperations:   "file_operations",
+	core.ExecuteFileOperations:    "execute_file_operations",
+	core.DetermineFileOrder:       "file_order",
+	core.GenerateFileContents:     "file_contents",
+	core.CreateOptionalComponents: "optional_components",
+	core.Done:                     "done",
+	core.VerifyProject:            "verify",
+	core.RepairProject:            "repair",
+	core.CheckProject:             "check",
+}
+
+// jobEvent is something published about a job: the completion of a step ("step"), the error
+// that failed it ("error"), or an event of a step, with the event's type
+type jobEvent struct {
+	Type    string    `json:"type"`
+	Step    string    `json:"step"`
+	Success bool      `json:"success"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// job is a generation submitted to the server. Jobs are saved as JSON in the jobs directory,
+// next to the zip archive of their project once they succeed.
+type job struct {
+	ID          string      `json:"id"`
+	Status      jobStatus   `json:"status"`
+	ProjectName string      `json:"project_name"`
+	CreatedAt   time.Time   `json:"created_at"`
+	StartedAt   *time.Time  `json:"started_at,omitempty"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Usage       []llm.Usage `json:"usage,omitempty"`
+	Events      []jobEvent  `json:"events,omitempty"`
+
+	// changed is closed and replaced when the job changes, to wake up event streams
+	changed chan struct{}
+}
+
+// jobServer runs the jobs submitted over HTTP with an engine's worker pool
+type jobServer struct {
+	core.DefaultStepPublisher
+	engine *Engine
+	// base is the server's configuration, which jobs are applied over
+	base   *core.Request
+	store  afero.Fs
+	dir    string
+	token  string
+	logger logger.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*job
+	// closing is closed when the server shuts down, to end the event streams
+	closing chan struct{}
+}
+
+// newJobServer creates a server that runs jobs with workers at a time and saves them in dir
+func newJobServer(base *core.Request, store afero.Fs, dir, token string, workers int, l logger.Logger) (*jobServer, error) {
+	s := &jobServer{
+		base:    base,
+		store:   store,
+		dir:     dir,
+		token:   token,
+		logger:  l,
+		jobs:    make(map[string]*job),
+		closing: make(chan struct{}),
+	}
+	engine, err := NewProjectEngine(s, l, workers, nil, "http://localhost:8000")
+	if err != nil {
+		return nil, err
+	}
+	s.engine = engine
+	return s, nil
+}
+
+// start loads the saved jobs and starts the engine. Jobs that were queued or running when the
+// server stopped are marked as failed.
+func (s *jobServer) start(ctx context.Context) error {
+	if err := s.store.MkdirAll(s.dir, fs.DefaultDirMode); err != nil {
+		return fmt.Errorf("error creating jobs directory: %w", err)
+	}
+	paths, err := afero.Glob(s.store, filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing jobs: %w", err)
+	}
+	for _, path := range paths {
+		content, err := afero.ReadFile(s.store, path)
+		if err != nil {
+			return fmt.Errorf("error reading job: %w", err)
+		}
+		j := &job{changed: make(chan struct{})}
+		if err := json.Unmarshal(content, j); err != nil {
+			return fmt.Errorf("error parsing job %s: %w", path, err)
+		}
+		if !j.Status.finished() {
+			j.Status = jobFailed
+			j.Error = "the server stopped before the job finished"
+			if err := s.save(j); err != nil {
+				return err
+			}
+		}
+		s.jobs[j.ID] = j
+	}
+	s.engine.Start(ctx)
+	return nil
+}
+
+// handler returns the HTTP handler of the server's API
+func (s *jobServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return s.authenticate(mux)
+}
+
+// authenticate requires the server's bearer token, if it has one
+func (s *jobServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			header := r.Header.Get("Authorization")
+			token := strings.TrimPrefix(header, "Bearer ")
+			if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, "invalid or missing token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleJobs lists the jobs on GET and submits a job on POST
+func (s *jobServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		jobs := make([]job, 0, len(s.jobs))
+		for _, j := range s.jobs {
+			summary := *j
+			summary.Events = nil
+			jobs = append(jobs, summary)
+		}
+		s.mu.Unlock()
+		sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.After(jobs[k].CreatedAt) })
+		writeJSON(w, http.StatusOK, jobs)
+	case http.MethodPost:
+		s.submit(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleJob serves /jobs/{id}, /jobs/{id}/events and /jobs/{id}/download
+func (s *jobServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("job %q not found", id))
+		return
+	}
+
+	switch action {
+	case "":
+		s.mu.Lock()
+		snapshot := *j
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, snapshot)
+	case "events":
+		s.streamEvents(w, r, j)
+	case "download":
+		s.download(w, r, j)
+	default:
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown path %s", r.URL.Path))
+	}
+}
+
+// submit creates a job from a JSON object of configuration keys and queues it
+func (s *jobServer) submit(w http.ResponseWriter, r *http.Request) {
+	var values map[string]interface{}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err := dec.Decode(&values); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid job: %v", err))
+		return
+	}
+	if err := checkJobKeys(values, ""); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := s.base.Clone()
+	if err := config.Apply(req, config.Origins{}, values, config.Origin{Layer: jobLayer}); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.ProjectName == "" || req.ProjectDescription == "" {
+		writeJSONError(w, http.StatusBadRequest, "a job needs a project_name and a project_description")
+		return
+	}
+	req.Usage = llm.NewUsageTracker()
+
+	id, err := newJobID()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	j := &job{
+		ID:          id,
+		Status:      jobQueued,
+		ProjectName: req.ProjectName,
+		CreatedAt:   time.Now().UTC(),
+		changed:     make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.jobs[id] = j
+	err = s.save(j)
+	snapshot := *j
+	s.mu.Unlock()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var projectFS *fs.FileSystem
+	resultChan := s.engine.Submit(ExecutionRequest{
+		ID:      id,
+		Request: req,
+		NewFS: func() *fs.FileSystem {
+			s.update(j, func() {
+				now := time.Now().UTC()
+				j.Status = jobRunning
+				j.StartedAt = &now
+			})
+			projectFS = fs.NewMemoryFileSystem()
+			return projectFS
+		},
+	})
+	go func() {
+		err := <-resultChan
+		if err == nil {
+			err = projectFS.WriteArchiveFile(s.store, s.archivePath(id), fs.ArchiveZip)
+		}
+		status := jobSucceeded
+		if err != nil {
+			status = jobFailed
+			s.logger.Error(fmt.Sprintf("Job %s failed: %v", id, err))
+		}
+		s.update(j, func() {
+			now := time.Now().UTC()
+			j.FinishedAt = &now
+			j.Usage = req.Usage.Usage()
+			j.Status = status
+			if err != nil {
+				j.Error = err.Error()
+			}
+		})
+	}()
+
+	w.Header().Set("Location", "/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// streamEvents sends the events of a job as server-sent events until it finishes, starting
+// after the Last-Event-ID of a reconnecting client. A final "done" event holds the job.
+func (s *jobServer) streamEvents(w http.ResponseWriter, r *http.Request, j *job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	next := 0
+	if last, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		next = last + 1
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		s.mu.Lock()
+		var events []jobEvent
+		if next < len(j.Events) {
+			events = append(events, j.Events[next:]...)
+		}
+		snapshot := *j
+		changed := j.changed
+		s.mu.Unlock()
+
+		for _, event := range events {
+			writeServerSentEvent(w, strconv.Itoa(next), event.Type, event)
+			next++
+		}
+		if snapshot.Status.finished() {
+			snapshot.Events = nil
+			writeServerSentEvent(w, "", "done", snapshot)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// writeServerSentEvent writes an event with a JSON payload
+func writeServerSentEvent(w io.Writer, id, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// download sends the zip archive of a succeeded job's project
+func (s *jobServer) download(w http.ResponseWriter, r *http.Request, j *job) {
+	s.mu.Lock()
+	status, name := j.Status, j.ProjectName
+	s.mu.Unlock()
+	if status != jobSucceeded {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("job is %s", status))
+		return
+	}
+	archive, err := s.store.Open(s.archivePath(j.ID))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "project archive not found")
+		return
+	}
+	defer archive.Close()
+	info, err := archive.Stat()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", utils.FormatProjectName(name)+".zip"))
+	http.ServeContent(w, r, "", info.ModTime(), archive)
+}
+
+// PublishRequestStep records the completion of a step of a job
+func (s *jobServer) PublishRequestStep(requestID string, step core.StepType) {
+	s.publish(requestID, jobEvent{Type: "step", Step: stepNames[step], Success: true})
+}
+
+// RequestError records the error of the step that failed a job
+func (s *jobServer) RequestError(requestID string, step core.StepType, err error) {
+	s.publish(requestID, jobEvent{Type: "error", Step: stepNames[step], Message: err.Error()})
+}
+
+// PublishRequestEvent records an event of a step of a job
+func (s *jobServer) PublishRequestEvent(requestID string, event core.Event) {
+	s.publish(requestID, jobEvent{Type: string(event.Type), Step: stepNames[event.Step], Success: event.Success, Message: event.Message})
+}
+
+// publish adds an event to a job
+func (s *jobServer) publish(id string, event jobEvent) {
+	event.Time = time.Now().UTC()
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.update(j, func() { j.Events = append(j.Events, event) })
+}
+
+// update changes a job, saves it and wakes up its event streams
+func (s *jobServer) update(j *job, change func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change()
+	if err := s.save(j); err != nil {
+		s.logger.Error(fmt.Sprintf("Error saving job %s: %v", j.ID, err))
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// save writes a job to the jobs directory. The caller must hold s.mu.
+func (s *jobServer) save(j *job) error {
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding job: %w", err)
+	}
+	path := filepath.Join(s.dir, j.ID+".json")
+	if err := afero.WriteFile(s.store, path, content, 0o600); err != nil {
+		return fmt.Errorf("error saving job: %w", err)
+	}
+	return nil
+}
+
+// archivePath returns the path of the zip archive of a job's project
+func (s *jobServer) archivePath(id string) string {
+	return filepath.Join(s.dir, id+".zip")
+}
+
+// checkJobKeys rejects the configuration keys a job can't set. Unknown keys are left to
+// config.Apply, which suggests the closest key.
+func checkJobKeys(values map[string]interface{}, prefix string) error {
+	for key, value := range values {
+		key = prefix + key
+		if nested, ok := value.(map[string]interface{}); ok && !config.IsKey(key) {
+			if err := checkJobKeys(nested, key+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if config.IsKey(key) && !jobKeys[key] {
+			return fmt.Errorf("%s can only be set in the server's configuration", key)
+		}
+	}
+	return nil
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v) // nolint:errcheck
+}
+
+// writeJSONError writes an error response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// defaultJobsDir returns the directory jobs are saved to by default
+func defaultJobsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user home directory: %w", err)
+	}
+	return filepath.Join(home, ".boil", "jobs"), nil
+}
+
+// runServe serves the job API until interrupted, then stops accepting requests and cancels
+// the running jobs
+func runServe(f serveFlags) error {
+	base, _, err := loadRequest(f.config, f.profile)
+	if err != nil {
+		return err
+	}
+	dir := f.jobsDir
+	if dir == "" {
+		if dir, err = defaultJobsDir(); err != nil {
+			return err
+		}
+	}
+	token := f.token
+	if token == "" {
+		token = os.Getenv("BOIL_SERVE_TOKEN")
+	}
+
+	InitLogger()
+	s, err := newJobServer(base, afero.NewOsFs(), dir, token, f.concurrency, GetLogger())
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+	if err := s.start(jobsCtx); err != nil {
+		return err
+	}
+
+	srv := &http.Server{Addr: f.addr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	errChan := make(chan error, 1)
+	go func() { errChan <- srv.ListenAndServe() }()
+	if token == "" {
+		fmt.Println("Warning: no --token is set, so anyone who can reach the server can submit jobs")
+	}
+	fmt.Printf("Serving the boil API on %s, saving jobs to %s\n", f.addr, dir)
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Println("Shutting down...")
+	close(s.closing)
+	cancelJobs()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	s.engine.Shutdown(5 * time.Second)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJobServer starts a job server whose jobs generate a one-file project
+func newTestJobServer(t *testing.T, store afero.Fs, token string) (*jobServer, *httptest.Server) {
+	t.Helper()
+	s, err := newJobServer(&core.Request{}, store, "jobs", token, 2, nil)
+	require.NoError(t, err)
+	s.engine.newClient = func(r *core.Request, kind string, opts clientOptions) (llm.LlmClient, error) {
+		return &projectLLM{project: r.ProjectName}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, s.start(ctx))
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+		s.engine.Shutdown(time.Second)
+	})
+	return s, srv
+}
+
+// submitJob posts a job and returns the response
+func submitJob(t *testing.T, srv *httptest.Server, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/jobs", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// readEvents reads a stream of server-sent events until it ends, returning the event names
+// and the data of the last event
+func readEvents(t *testing.T, body io.Reader) ([]string, string) {
+	t.Helper()
+	var names []string
+	var data string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			names = append(names, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return names, data
+}
+
+func TestJobServer_Job(t *testing.T) {
+	store := afero.NewMemMapFs()
+	_, srv := newTestJobServer(t, store, "")
+
+	resp := submitJob(t, srv, `{"project_name": "My API", "project_description": "An API"}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var submitted job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&submitted))
+	assert.Equal(t, "/jobs/"+submitted.ID, resp.Header.Get("Location"))
+	assert.Equal(t, "My API", submitted.ProjectName)
+
+	events, err := http.Get(srv.URL + "/jobs/" + submitted.ID + "/events")
+	require.NoError(t, err)
+	defer events.Body.Close()
+	assert.Equal(t, "text/event-stream", events.Header.Get("Content-Type"))
+	names, data := readEvents(t, events.Body)
+	require.NotEmpty(t, names)
+	assert.Equal(t, "step", names[0])
+	assert.Equal(t, "done", names[len(names)-1])
+	var done job
+	require.NoError(t, json.Unmarshal([]byte(data), &done))
+	assert.Equal(t, jobSucceeded, done.Status)
+
+	status, err := http.Get(srv.URL + "/jobs/" + submitted.ID)
+	require.NoError(t, err)
+	defer status.Body.Close()
+	var finished job
+	require.NoError(t, json.NewDecoder(status.Body).Decode(&finished))
+	assert.Equal(t, jobSucceeded, finished.Status)
+	assert.NotNil(t, finished.FinishedAt)
+	assert.Equal(t, "project_details", finished.Events[0].Step)
+
+	download, err := http.Get(srv.URL + "/jobs/" + submitted.ID + "/download")
+	require.NoError(t, err)
+	defer download.Body.Close()
+	require.Equal(t, http.StatusOK, download.StatusCode)
+	assert.Equal(t, "application/zip", download.Header.Get("Content-Type"))
+	assert.Contains(t, download.Header.Get("Content-Disposition"), `filename="My-API.zip"`)
+	content, err := io.ReadAll(download.Body)
+	require.NoError(t, err)
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 1)
+	assert.Equal(t, "main.txt", archive.File[0].Name)
+
+	saved, err := afero.ReadFile(store, "jobs/"+submitted.ID+".json")
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), `"status": "succeeded"`)
+}
+
+func TestJobServer_InvalidJobs(t *testing.T) {
+	_, srv := newTestJobServer(t, afero.NewMemMapFs(), "")
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"not json", `project`, "invalid job"},
+		{"missing description", `{"project_name": "api"}`, "project_description"},
+		{"server key", `{"project_name": "api", "project_description": "An API", "verify_command": "rm -rf /"}`, "verify_command can only be set in the server's configuration"},
+		{"nested server key", `{"project_name": "api", "project_description": "An API", "rate_limit": {"requests_per_minute": 1}}`, "rate_limit.requests_per_minute"},
+		{"unknown key", `{"project_name": "api", "project_description": "An API", "readm": true}`, `unknown configuration key`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := submitJob(t, srv, tt.body)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.want)
+		})
+	}
+
+	resp, err := http.Get(srv.URL + "/jobs/unknown")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestJobServer_Token(t *testing.T) {
+	_, srv := newTestJobServer(t, afero.NewMemMapFs(), "secret")
+
+	for _, header := range []string{"", "secret", "Bearer wrong"} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/jobs", nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "Authorization: %q", header)
+		assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/jobs", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestJobServer_LoadsSavedJobs(t *testing.T) {
+	store := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(store, "jobs/done.json", []byte(`{"id": "done", "status": "succeeded", "project_name": "api"}`), 0o600))
+	require.NoError(t, afero.WriteFile(store, "jobs/running.json", []byte(`{"id": "running", "status": "running", "project_name": "api"}`), 0o600))
+
+	s, _ := newTestJobServer(t, store, "")
+
+	assert.Equal(t, jobSucceeded, s.jobs["done"].Status)
+	assert.Equal(t, jobFailed, s.jobs["running"].Status)
+	assert.Equal(t, "the server stopped before the job finished", s.jobs["running"].Error)
+	saved, err := afero.ReadFile(store, "jobs/running.json")
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), `"status": "failed"`)
+}
+
+func TestJobServer_Fallbacks(t *testing.T) {
+	s, srv := newTestJobServer(t, afero.NewMemMapFs(), "")
+	s.base = &core.Request{Fallbacks: []string{"openai/a", "openai/b"}}
+	var mu sync.Mutex
+	fallbacks := make(map[string][]string)
+	s.engine.newClient = func(r *core.Request, kind string, opts clientOptions) (llm.LlmClient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fallbacks[r.ProjectName] = append([]string(nil), r.Fallbacks...)
+		return &projectLLM{project: r.ProjectName}, nil
+	}
+
+	var ids []string
+	for _, body := range []string{
+		`{"project_name": "users", "project_description": "Users API", "fallbacks": ["anthropic/x"]}`,
+		`{"project_name": "billing", "project_description": "Billing worker", "fallbacks": ["anthropic/y", "openai/c", "openai/d"]}`,
+	} {
+		resp := submitJob(t, srv, body)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		var submitted job
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&submitted))
+		ids = append(ids, submitted.ID)
+	}
+	for _, id := range ids {
+		events, err := http.Get(srv.URL + "/jobs/" + id + "/events")
+		require.NoError(t, err)
+		readEvents(t, events.Body)
+		events.Body.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"anthropic/x"}, fallbacks["users"])
+	assert.Equal(t, []string{"anthropic/y", "openai/c", "openai/d"}, fallbacks["billing"])
+	assert.Equal(t, []string{"openai/a", "openai/b"}, s.base.Fallbacks)
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/templates"
+	"github.com/spf13/afero"
+)
+
+type templateSaveFlags struct {
+	// from is a generated project whose description, model and file tree are saved
+	from         string
+	description  string
+	instructions string
+	model        string
+	// components are the optional components to generate, or nil to ask for them
+	components *templates.Components
+	// pinTree keeps the file tree of the project in the template
+	pinTree bool
+	force   bool
+}
+
+// templateStore returns the store of the user's templates
+func templateStore() (*templates.Store, error) {
+	dir, err := templates.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return templates.NewStore(afero.NewOsFs(), dir), nil
+}
+
+// loadTemplate reads a template from the user's templates
+func loadTemplate(name string) (*templates.Template, error) {
+	store, err := templateStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Load(name)
+}
+
+func runTemplateList(out io.Writer) error {
+	store, err := templateStore()
+	if err != nil {
+		return err
+	}
+	names, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(out, "No templates saved. Create one with \"boil template save <name>\".")
+		return nil
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	faint := lipgloss.NewStyle().Faint(true)
+	for _, name := range names {
+		t, err := store.Load(name)
+		if err != nil {
+			fmt.Fprintf(out, "%s %s\n", nameStyle.Render(name), faint.Render(fmt.Sprintf("(%v)", err)))
+			continue
+		}
+		fmt.Fprintf(out, "%s %s\n", nameStyle.Render(name), faint.Render(summarize(t.Description, 60)))
+	}
+	return nil
+}
+
+func runTemplateShow(name string, out io.Writer) error {
+	t, err := loadTemplate(name)
+	if err != nil {
+		return err
+	}
+	content, err := templates.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(content)
+	return err
+}
+
+func runTemplateSave(f templateSaveFlags, name string, out io.Writer) error {
+	t := &templates.Template{Name: name}
+	if f.from != "" {
+		project, err := fs.LoadDir(afero.NewOsFs(), f.from)
+		if err != nil {
+			return err
+		}
+		metadata, err := core.LoadMetadata(project)
+		if err != nil {
+			return err
+		}
+		if metadata == nil {
+			return fmt.Errorf("%s has no saved project metadata (%s)", f.from, core.MetadataPath)
+		}
+		t.Description = metadata.Description
+		t.ModelName = metadata.ModelName
+		if f.pinTree {
+			t.FileTree = metadata.FileTree
+		}
+	}
+
+	if f.description != "" {
+		t.Description = f.description
+	}
+	if f.instructions != "" {
+		t.Instructions = f.instructions
+	}
+	if f.model != "" {
+		t.ModelName = f.model
+	}
+	t.Components = f.components
+
+	store, err := templateStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Save(t, f.force); err != nil {
+		if errors.Is(err, templates.ErrExists) {
+			return fmt.Errorf("%w (use --force to replace it)", err)
+		}
+		return err
+	}
+
+	nameStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	fmt.Fprintf(out, "Saved template %s\n", nameStyle.Render(name))
+	return nil
+}
+
+func runTemplateDelete(name string, out io.Writer) error {
+	store, err := templateStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(name); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deleted template %s\n", name)
+	return nil
+}
+
+// parseComponents parses a comma-separated list of optional components, such as
+// "git,readme". "none" selects no components.
+func parseComponents(value string) (*templates.Components, error) {
+	c := &templates.Components{}
+	if strings.TrimSpace(value) == "none" {
+		return c, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		switch strings.TrimSpace(name) {
+		case "git":
+			c.GitRepo = true
+		case "gitignore":
+			c.GitIgnore = true
+		case "readme":
+			c.Readme = true
+		case "dockerfile":
+			c.Dockerfile = true
+		default:
+			return nil, fmt.Errorf("unknown component %q: use git, gitignore, readme, dockerfile or none", name)
+		}
+	}
+	return c, nil
+}
+
+// summarize returns the first line of s, shortened to at most n characters
+func summarize(s string, n int) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
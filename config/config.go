@@ -1,37 +1,464 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	
-	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
 	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/credentials"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// ProjectPath is the project configuration file, looked up in the current directory
+const ProjectPath = ".boil.yaml"
+
+// EnvPrefix prefixes the environment variables of configuration keys, as in BOIL_MODEL_NAME
+const EnvPrefix = "BOIL_"
+
+// ProfileEnv selects the profile when no profile is given in the Options
+const ProfileEnv = "BOIL_PROFILE"
+
+// Keys of configuration files that hold profiles rather than request values
+const (
+	ProfilesKey       = "profiles"
+	DefaultProfileKey = "default_profile"
+)
+
+// Layers of the configuration, from lowest to highest precedence
+const (
+	LayerDefault     = "default"
+	LayerCredentials = "credential store"
+	LayerUser        = "user config"
+	LayerProject     = "project config"
+	LayerProfile     = "profile"
+	LayerEnv         = "env"
+	LayerFlag        = "flag"
 )
 
-func LoadConfig(configPath string) (*core.Request, error) {
+// Origin is where the effective value of a configuration key comes from
+type Origin struct {
+	Layer string
+	// Name is the file, environment variable or flag that set the value, if any
+	Name string
+}
+
+func (o Origin) String() string {
+	if o.Name == "" {
+		return o.Layer
+	}
+	return fmt.Sprintf("%s (%s)", o.Layer, o.Name)
+}
+
+// Origins maps configuration keys to the origin of their values
+type Origins map[string]Origin
+
+// Options select the configuration layers to load
+type Options struct {
+	// Fs is the file system configuration files are read from, defaulting to the OS's
+	Fs afero.Fs
+	// ConfigPath replaces the user configuration file. Unlike the user configuration file,
+	// it must exist.
+	ConfigPath string
+	// UserPath is the user configuration file, defaulting to ~/.boil/config.yaml
+	UserPath string
+	// ProjectPath is the project configuration file, defaulting to ProjectPath
+	ProjectPath string
+	// Env holds the environment as KEY=VALUE pairs, defaulting to os.Environ()
+	Env []string
+	// Credentials provides the API keys that no other layer sets, if not nil
+	Credentials credentials.Store
+	// Profile selects a profile of the configuration files, overriding ProfileEnv and the
+	// default_profile of the files
+	Profile string
+}
+
+// DefaultUserPath returns the path of the user configuration file
+func DefaultUserPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user home directory: %w", err)
+	}
+	return filepath.Join(home, ".boil", "config.yaml"), nil
+}
+
+// Load resolves the request defaults from the configuration layers: built-in defaults, the
+// user configuration file, the project configuration file, the selected profile and BOIL_
+// environment variables, each overriding the previous ones. Missing user and project
+// configuration files are treated as empty. API keys set by none of the layers are read from
+// the credential store.
+func Load(opts Options) (*core.Request, Origins, error) {
+	if opts.Fs == nil {
+		opts.Fs = afero.NewOsFs()
+	}
+	if opts.Env == nil {
+		opts.Env = os.Environ()
+	}
+	if opts.ProjectPath == "" {
+		opts.ProjectPath = ProjectPath
+	}
+
+	req := core.DefaultRequest()
+	origins := make(Origins)
+	for _, key := range Keys() {
+		origins[key] = Origin{Layer: LayerDefault}
+	}
+	env := envMap(opts.Env)
+	req.APIKey = env["OPENAI_API_KEY"]
+	if req.APIKey != "" {
+		origins["openai_api_key"] = Origin{Layer: LayerEnv, Name: "OPENAI_API_KEY"}
+	}
+	req.AnthropicAPIKey = env["ANTHROPIC_API_KEY"]
+	if req.AnthropicAPIKey != "" {
+		origins["anthropic_api_key"] = Origin{Layer: LayerEnv, Name: "ANTHROPIC_API_KEY"}
+	}
+
+	userPath, required := opts.ConfigPath, true
+	if userPath == "" {
+		userPath, required = opts.UserPath, false
+		if userPath == "" {
+			var err error
+			userPath, err = DefaultUserPath()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	user, err := loadFile(opts.Fs, req, origins, userPath, LayerUser, required)
+	if err != nil {
+		return nil, nil, err
+	}
+	project, err := loadFile(opts.Fs, req, origins, opts.ProjectPath, LayerProject, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile := opts.Profile
+	for _, name := range []string{env[ProfileEnv], project.defaultProfile, user.defaultProfile} {
+		if profile == "" {
+			profile = name
+		}
+	}
+	if profile != "" {
+		if err := applyProfile(req, origins, profile, user, project); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, key := range Keys() {
+		name := EnvName(key)
+		if value, ok := env[name]; ok {
+			if err := Set(req, origins, key, value, Origin{Layer: LayerEnv, Name: name}); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if opts.Credentials != nil {
+		for _, provider := range llm.Providers() {
+			key := APIKeyName(provider)
+			if origins[key].Layer != LayerDefault {
+				continue
+			}
+			secret, err := getCredential(opts.Credentials, provider, profile)
+			if errors.Is(err, credentials.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := Set(req, origins, key, secret, Origin{Layer: LayerCredentials, Name: opts.Credentials.String()}); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return req, origins, nil
+}
+
+// CredentialName returns the name of a provider's API key in the credential store. Keys
+// stored for a profile are used instead of the provider's key when the profile is selected.
+func CredentialName(provider, profile string) string {
+	if profile == "" {
+		return provider
+	}
+	return provider + ":" + strings.ToLower(profile)
+}
+
+// getCredential reads the API key of a provider for a profile from the credential store,
+// falling back to the provider's key
+func getCredential(store credentials.Store, provider, profile string) (string, error) {
+	if profile != "" {
+		secret, err := store.Get(CredentialName(provider, profile))
+		if !errors.Is(err, credentials.ErrNotFound) {
+			return secret, err
+		}
+	}
+	return store.Get(provider)
+}
+
+// APIKeyName returns the configuration key of a provider's API key
+func APIKeyName(provider string) string {
+	if provider == llm.ProviderAnthropic {
+		return "anthropic_api_key"
+	}
+	return "openai_api_key"
+}
+
+// fileProfiles holds the profiles of a configuration file
+type fileProfiles struct {
+	path           string
+	defaultProfile string
+	// profiles maps lowercase profile names to their configuration values
+	profiles map[string]map[string]interface{}
+}
+
+// loadFile applies a configuration file to the request and returns its profiles. Missing
+// files are skipped unless required.
+func loadFile(fs afero.Fs, req *core.Request, origins Origins, path, layer string, required bool) (*fileProfiles, error) {
+	profiles := &fileProfiles{path: path}
+	if _, err := fs.Stat(path); err != nil {
+		if os.IsNotExist(err) && !required {
+			return profiles, nil
+		}
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
 	v := viper.New()
-	v.SetConfigFile(configPath)
-	
+	v.SetFs(fs)
+	v.SetConfigFile(path)
+	if filepath.Ext(path) == "" {
+		v.SetConfigType("yaml")
+	}
 	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("error reading config file: %w", err)
+		return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	resetLists(req, v.AllKeys())
+	if err := v.Unmarshal(req); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config file %s: %w", path, err)
+	}
+	for _, key := range v.AllKeys() {
+		if _, ok := origins[key]; !ok {
+			// Values of unknown nested keys are recorded under their top-level key
+			key, _, _ = strings.Cut(key, ".")
+		}
+		if _, ok := origins[key]; ok {
+			origins[key] = Origin{Layer: layer, Name: path}
+		}
 	}
 
-	// Start with default values
-	req := core.DefaultRequest()
+	profiles.defaultProfile = v.GetString(DefaultProfileKey)
+	if v.IsSet(ProfilesKey) {
+		raw, ok := v.Get(ProfilesKey).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("error reading config file %s: %s must map profile names to configuration values", path, ProfilesKey)
+		}
+		profiles.profiles = make(map[string]map[string]interface{}, len(raw))
+		for name, values := range raw {
+			m, ok := values.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("error reading config file %s: profile %q must map keys to values", path, name)
+			}
+			profiles.profiles[strings.ToLower(name)] = m
+		}
+	}
+	return profiles, nil
+}
+
+// applyProfile applies a profile of the user and project configuration files to the request,
+// the project's values overriding the user's
+func applyProfile(req *core.Request, origins Origins, profile string, files ...*fileProfiles) error {
+	found := false
+	for _, file := range files {
+		nested, ok := file.profiles[strings.ToLower(profile)]
+		if !ok {
+			continue
+		}
+		found = true
+		values := flatten(nested, "")
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := origins[key]; !ok {
+				// Unknown keys are ignored like in the rest of the file
+				continue
+			}
+			origin := Origin{Layer: LayerProfile, Name: fmt.Sprintf("%s in %s", profile, file.path)}
+			if err := Set(req, origins, key, values[key], origin); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		var names []string
+		for _, file := range files {
+			for name := range file.profiles {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q (no profiles are defined)", profile)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q (available profiles: %s)", profile, strings.Join(dedupe(names), ", "))
+	}
+	return nil
+}
+
+// Apply sets the configuration keys of a mapping of values, such as an entry of a batch
+// manifest, nested mappings included. Unlike in configuration files, unknown keys are an
+// error.
+func Apply(req *core.Request, origins Origins, values map[string]interface{}, origin Origin) error {
+	flat := flatten(values, "")
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !IsKey(key) {
+			msg := fmt.Sprintf("unknown configuration key %q in %s", key, origin)
+			if s := suggestKey(key); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			return errors.New(msg)
+		}
+		if err := Set(req, origins, key, flat[key], origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Unmarshal config into the request struct
+// dedupe removes adjacent duplicates from sorted values
+func dedupe(values []string) []string {
+	var out []string
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Set sets a configuration key of the request, converting value to the key's type, and
+// records its origin
+func Set(req *core.Request, origins Origins, key string, value interface{}, origin Origin) error {
+	v := viper.New()
+	v.Set(key, value)
+	resetLists(req, []string{key})
 	if err := v.Unmarshal(req); err != nil {
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
-	}
-
-	// Use NewRequest to create the final request object
-	return core.NewRequest(
-		req.ProjectDescription,
-		req.ProjectName,
-		req.APIKey,
-		req.ModelName,
-		req.GitRepo,
-		req.GitIgnore,
-		req.Readme,
-		req.Dockerfile,
-	), nil
-}
\ No newline at end of file
+		return fmt.Errorf("invalid value for %s from %s: %w", key, origin, err)
+	}
+	if origins != nil {
+		origins[key] = origin
+	}
+	return nil
+}
+
+// EnvName returns the environment variable of a configuration key, such as
+// BOIL_MODELS_CODE for models.code
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Keys returns the configuration keys of a request, in declaration order. The keys of
+// nested values are joined with dots, as in models.code.
+func Keys() []string {
+	return structKeys(reflect.TypeOf(core.Request{}), "")
+}
+
+// structKeys returns the configuration keys of a struct's fields, prefixed with prefix
+func structKeys(t reflect.Type, prefix string) []string {
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, structKeys(field.Type, prefix+key+".")...)
+			continue
+		}
+		keys = append(keys, prefix+key)
+	}
+	return keys
+}
+
+// Value returns the value of a configuration key of the request
+func Value(req *core.Request, key string) (interface{}, bool) {
+	v, ok := field(req, key)
+	if !ok || v.Kind() == reflect.Struct {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+// field returns the field of the request a configuration key sets
+func field(req *core.Request, key string) (reflect.Value, bool) {
+	v := reflect.ValueOf(req).Elem()
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct || name == "-" {
+			return reflect.Value{}, false
+		}
+		t := v.Type()
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("mapstructure") == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// resetLists clears the list fields of the keys about to be decoded into the request.
+// Decoding a list fills the existing one element by element, so a shorter list would
+// otherwise keep the tail of the value it replaces.
+func resetLists(req *core.Request, keys []string) {
+	for _, key := range keys {
+		if v, ok := field(req, key); ok && v.Kind() == reflect.Slice {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+}
+
+// flatten returns the values of a configuration map by dotted key
+func flatten(values map[string]interface{}, prefix string) map[string]interface{} {
+	flat := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if nested, ok := value.(map[string]interface{}); ok {
+			for k, v := range flatten(nested, prefix+key+".") {
+				flat[k] = v
+			}
+			continue
+		}
+		flat[prefix+key] = value
+	}
+	return flat
+}
+
+// envMap parses KEY=VALUE pairs
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			m[key] = value
+		}
+	}
+	return m
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/credentials"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestLoad(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(memFs, "/home/.boil/config.yaml", []byte("model_name: gpt-4o\nreadme: true\nverify_timeout: 10m\nproject_name: user\n"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/work/.boil.yaml", []byte("readme: false\ngit_repo: true\nproject_name: project\n"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/custom.yaml", []byte("dockerfile: true\n"), 0644))
+
+	t.Run("layers", func(t *testing.T) {
+		req, origins, err := Load(Options{
+			Fs:          memFs,
+			UserPath:    "/home/.boil/config.yaml",
+			ProjectPath: "/work/.boil.yaml",
+			Env:         []string{"BOIL_PROJECT_NAME=env", "BOIL_REPAIR=true", "OPENAI_API_KEY=sk-test", "HOME=/home"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "gpt-4o", req.ModelName)
+		assert.Equal(t, 10*time.Minute, req.VerifyTimeout)
+		assert.False(t, req.Readme)
+		assert.True(t, req.GitRepo)
+		assert.Equal(t, "env", req.ProjectName)
+		assert.True(t, req.Repair)
+		assert.Equal(t, "sk-test", req.APIKey)
+		assert.True(t, req.SaveMetadata)
+
+		assert.Equal(t, Origin{Layer: LayerUser, Name: "/home/.boil/config.yaml"}, origins["model_name"])
+		assert.Equal(t, Origin{Layer: LayerProject, Name: "/work/.boil.yaml"}, origins["readme"])
+		assert.Equal(t, Origin{Layer: LayerEnv, Name: "BOIL_PROJECT_NAME"}, origins["project_name"])
+		assert.Equal(t, Origin{Layer: LayerEnv, Name: "OPENAI_API_KEY"}, origins["openai_api_key"])
+		assert.Equal(t, Origin{Layer: LayerDefault}, origins["save_metadata"])
+		assert.Equal(t, "default", origins["save_metadata"].String())
+
+		require.NoError(t, Set(req, origins, "repair_rounds", 7, Origin{Layer: LayerFlag, Name: "--repair-rounds"}))
+		assert.Equal(t, 7, req.RepairRounds)
+		assert.Equal(t, "flag (--repair-rounds)", origins["repair_rounds"].String())
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		req, origins, err := Load(Options{Fs: memFs, UserPath: "/missing.yaml", ProjectPath: "/missing.yaml", Env: []string{}})
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-mini", req.ModelName)
+		assert.Empty(t, req.APIKey)
+		assert.Len(t, origins, len(Keys()))
+	})
+
+	t.Run("config path replaces the user config", func(t *testing.T) {
+		req, origins, err := Load(Options{Fs: memFs, ConfigPath: "/custom.yaml", UserPath: "/home/.boil/config.yaml", ProjectPath: "/missing.yaml", Env: []string{}})
+		require.NoError(t, err)
+		assert.True(t, req.Dockerfile)
+		assert.Equal(t, "gpt-4o-mini", req.ModelName)
+		assert.Equal(t, LayerUser, origins["dockerfile"].Layer)
+
+		_, _, err = Load(Options{Fs: memFs, ConfigPath: "/missing.yaml", Env: []string{}})
+		assert.Error(t, err)
+	})
+
+	t.Run("credential store", func(t *testing.T) {
+		keyring.MockInit()
+		store := credentials.NewKeyringStore()
+		require.NoError(t, store.Set("openai", "sk-stored"))
+		require.NoError(t, store.Set("anthropic", "sk-ant-stored"))
+
+		req, origins, err := Load(Options{Fs: memFs, UserPath: "/missing.yaml", ProjectPath: "/missing.yaml", Env: []string{"ANTHROPIC_API_KEY=sk-ant-env"}, Credentials: store})
+		require.NoError(t, err)
+		assert.Equal(t, "sk-stored", req.APIKey)
+		assert.Equal(t, Origin{Layer: LayerCredentials, Name: "OS keyring"}, origins["openai_api_key"])
+		assert.Equal(t, "sk-ant-env", req.AnthropicAPIKey, "keys set by other layers take precedence")
+
+		require.NoError(t, afero.WriteFile(memFs, "/work.yaml", []byte("profiles:\n  work: {}\n  personal: {}\n"), 0644))
+		require.NoError(t, store.Set(CredentialName("openai", "work"), "sk-work"))
+		for profile, want := range map[string]string{"work": "sk-work", "personal": "sk-stored"} {
+			req, _, err = Load(Options{Fs: memFs, UserPath: "/work.yaml", ProjectPath: "/missing.yaml", Env: []string{}, Credentials: store, Profile: profile})
+			require.NoError(t, err)
+			assert.Equal(t, want, req.APIKey, profile)
+		}
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(memFs, "/profiles/user.yaml", []byte("model_name: gpt-4o\nrepair_rounds: 2\ndefault_profile: fast\nprofiles:\n  fast:\n    model_name: gpt-4o-mini\n  Quality:\n    model_name: gpt-4.1\n    repair: true\n"), 0644))
+		require.NoError(t, afero.WriteFile(memFs, "/profiles/project.yaml", []byte("profiles:\n  quality:\n    repair_rounds: 5\n"), 0644))
+		opts := Options{Fs: memFs, UserPath: "/profiles/user.yaml", ProjectPath: "/profiles/project.yaml", Env: []string{}}
+
+		req, origins, err := Load(opts)
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-mini", req.ModelName, "default_profile is applied")
+		assert.Equal(t, "profile (fast in /profiles/user.yaml)", origins["model_name"].String())
+
+		opts.Profile = "quality"
+		req, origins, err = Load(opts)
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4.1", req.ModelName)
+		assert.True(t, req.Repair)
+		assert.Equal(t, 5, req.RepairRounds, "the project's profile overrides the user's")
+		assert.Equal(t, Origin{Layer: LayerProfile, Name: "quality in /profiles/project.yaml"}, origins["repair_rounds"])
+
+		opts.Env = []string{"BOIL_MODEL_NAME=gpt-4"}
+		req, _, err = Load(opts)
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4", req.ModelName, "env overrides profiles")
+
+		opts.Profile = ""
+		opts.Env = []string{"BOIL_PROFILE=quality"}
+		req, _, err = Load(opts)
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4.1", req.ModelName)
+
+		opts.Profile = "cheap"
+		_, _, err = Load(opts)
+		assert.EqualError(t, err, `unknown profile "cheap" (available profiles: fast, quality)`)
+	})
+
+	t.Run("invalid env value", func(t *testing.T) {
+		_, _, err := Load(Options{Fs: memFs, UserPath: "/missing.yaml", ProjectPath: "/missing.yaml", Env: []string{"BOIL_VERIFY=maybe"}})
+		assert.ErrorContains(t, err, "BOIL_VERIFY")
+	})
+}
+
+func TestValidateFile(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(memFs, "/config.yaml", []byte("model_name: gpt-4o\nmodel_nmae: gpt-4o\nreadme: maybe\nrepair_rounds: 2\nverify_timeout: soon\ngit_repo:\n  enabled: true\n"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/valid.yaml", []byte("# defaults\nmodel_name: gpt-4o\nproject_name: 42\nverify_timeout: 2m\n"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/empty.yaml", nil, 0644))
+
+	problems, err := ValidateFile(memFs, "/config.yaml")
+	require.NoError(t, err)
+	require.Len(t, problems, 4)
+	assert.Equal(t, "/config.yaml:2: unknown key \"model_nmae\" (did you mean \"model_name\"?)", problems[0].String())
+	assert.Equal(t, "readme", problems[1].Key)
+	assert.Equal(t, 3, problems[1].Line)
+	assert.Contains(t, problems[1].Message, "true or false")
+	assert.Contains(t, problems[2].Message, "duration")
+	assert.Contains(t, problems[3].Message, "mapping")
+
+	problems, err = ValidateFile(memFs, "/valid.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	require.NoError(t, afero.WriteFile(memFs, "/profiles.yaml", []byte("default_profile: fast\nprofiles:\n  fast:\n    model_name: gpt-4o-mini\n    verify: sometimes\n  broken: true\n"), 0644))
+	problems, err = ValidateFile(memFs, "/profiles.yaml")
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+	assert.Equal(t, "/profiles.yaml:5: invalid value for profiles.fast.verify: expected true or false", problems[0].String())
+	assert.Contains(t, problems[1].Message, `profile "broken"`)
+
+	problems, err = ValidateFile(memFs, "/empty.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestLoad_StepModels(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(memFs, "/config.yaml", []byte("model_name: gpt-4o\nmodels:\n  planning: gpt-4o-mini\n  docs: gpt-4.1-nano\nprofiles:\n  big:\n    models:\n      code: gpt-4.1\n"), 0644))
+
+	req, origins, err := Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Profile: "big", Env: []string{"BOIL_MODELS_DOCS=gpt-4o"}})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", req.Model(core.ModelPlanning))
+	assert.Equal(t, "gpt-4.1", req.Model(core.ModelCode))
+	assert.Equal(t, "gpt-4o", req.Model(core.ModelDocs))
+	assert.Equal(t, Origin{Layer: LayerUser, Name: "/config.yaml"}, origins["models.planning"])
+	assert.Equal(t, LayerProfile, origins["models.code"].Layer)
+	assert.Equal(t, Origin{Layer: LayerEnv, Name: "BOIL_MODELS_DOCS"}, origins["models.docs"])
+
+	value, ok := Value(req, "models.code")
+	assert.True(t, ok)
+	assert.Equal(t, "gpt-4.1", value)
+	_, ok = Value(req, "models")
+	assert.False(t, ok)
+
+	require.NoError(t, afero.WriteFile(memFs, "/invalid.yaml", []byte("models:\n  planing: gpt-4o-mini\n  code: 3\nprofiles:\n  fast:\n    models: gpt-4o\n"), 0644))
+	problems, err := ValidateFile(memFs, "/invalid.yaml")
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+	assert.Equal(t, `/invalid.yaml:2: unknown key "models.planing" (did you mean "models.planning"?)`, problems[0].String())
+	assert.Equal(t, "/invalid.yaml:6: invalid value for profiles.fast.models: expected a mapping of keys to values", problems[1].String())
+}
+
+func TestLoad_ShorterListOverrides(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(memFs, "/config.yaml", []byte("fallbacks: [openai/a, openai/b]\nprofiles:\n  alt:\n    fallbacks: [anthropic/x]\n"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "/project.yaml", []byte("fallbacks: [openai/c]\n"), 0644))
+
+	req, _, err := Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/project.yaml", Env: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openai/c"}, req.Fallbacks)
+
+	req, _, err = Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Profile: "alt", Env: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anthropic/x"}, req.Fallbacks)
+
+	req, _, err = Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Env: []string{"BOIL_FALLBACKS=anthropic/y"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anthropic/y"}, req.Fallbacks)
+
+	require.NoError(t, Set(req, nil, "fallbacks", []string{"openai/z"}, Origin{Layer: LayerFlag}))
+	assert.Equal(t, []string{"openai/z"}, req.Fallbacks)
+}
+
+func TestApply(t *testing.T) {
+	req := core.DefaultRequest()
+	origins := make(Origins)
+	origin := Origin{Layer: "batch manifest", Name: "services.yaml"}
+	require.NoError(t, Apply(req, origins, map[string]interface{}{
+		"project_name": "users",
+		"repair":       true,
+		"models":       map[string]interface{}{"code": "gpt-4.1"},
+	}, origin))
+	assert.Equal(t, "users", req.ProjectName)
+	assert.True(t, req.Repair)
+	assert.Equal(t, "gpt-4.1", req.Models.Code)
+	assert.Equal(t, origin, origins["models.code"])
+
+	err := Apply(req, origins, map[string]interface{}{"readmee": true}, origin)
+	assert.EqualError(t, err, `unknown configuration key "readmee" in batch manifest (services.yaml) (did you mean "readme"?)`)
+	assert.Error(t, Apply(req, origins, map[string]interface{}{"repair_rounds": "many"}, origin))
+}
+
+func TestValidateRequest(t *testing.T) {
+	req := core.DefaultRequest()
+	assert.Empty(t, ValidateRequest(req))
+
+	req.Provider = "anthropic"
+	problems := ValidateRequest(req)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "model_name", problems[0].Key)
+
+	req.ModelName = "claude-3-5-sonnet-latest"
+	assert.Empty(t, ValidateRequest(req))
+
+	req.Models.Code = "gpt-4o"
+	problems = ValidateRequest(req)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "models.code", problems[0].Key)
+	req.Models.Code = ""
+
+	req.Fallbacks = []string{"claude-3-5-haiku-latest", "openai/gpt-4o", "acme/big", "openai/gpt-5-ultra"}
+	problems = ValidateRequest(req)
+	require.Len(t, problems, 2)
+	assert.Contains(t, problems[0].Message, `unknown provider "acme"`)
+	assert.Contains(t, problems[1].Message, `fallback model "gpt-5-ultra" is not known for provider openai`)
+	req.Fallbacks = nil
+
+	req.RateLimit.TokensPerMinute = -1
+	problems = ValidateRequest(req)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "rate_limit", problems[0].Key)
+	req.RateLimit.TokensPerMinute = 0
+
+	req.Provider = "acme"
+	problems = ValidateRequest(req)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "provider", problems[0].Key)
+}
+
+func TestSetFileValue(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(memFs, "/config.yaml", []byte("# my defaults\nmodel_name: gpt-4o # fast enough\nreadme: true\n"), 0644))
+
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "", "model_name", "gpt-4.1"))
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "", "repair_rounds", "5"))
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "", "project_name", "true"))
+	content, err := afero.ReadFile(memFs, "/config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "# my defaults\nmodel_name: gpt-4.1 # fast enough\nreadme: true\nrepair_rounds: 5\nproject_name: \"true\"\n", string(content))
+
+	req, _, err := Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Env: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4.1", req.ModelName)
+	assert.Equal(t, 5, req.RepairRounds)
+	assert.Equal(t, "true", req.ProjectName)
+
+	require.NoError(t, SetFileValue(memFs, "/new/config.yaml", "", "dockerfile", "true"))
+	content, err = afero.ReadFile(memFs, "/new/config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "dockerfile: true\n", string(content))
+
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "fast", "model_name", "gpt-4o-mini"))
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "fast", "repair", "true"))
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "", "default_profile", "fast"))
+	req, _, err = Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Env: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", req.ModelName)
+	assert.True(t, req.Repair)
+	removed, err := UnsetFileValue(memFs, "/config.yaml", "fast", "repair")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = UnsetFileValue(memFs, "/config.yaml", "", "readme")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = UnsetFileValue(memFs, "/config.yaml", "", "readme")
+	require.NoError(t, err)
+	assert.False(t, removed)
+	content, err = afero.ReadFile(memFs, "/config.yaml")
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "readme")
+
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "fast", "models.code", "gpt-4.1"))
+	content, err = afero.ReadFile(memFs, "/config.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "  fast:\n    model_name: gpt-4o-mini\n    models:\n      code: gpt-4.1\n")
+	removed, err = UnsetFileValue(memFs, "/config.yaml", "fast", "models.code")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	require.NoError(t, SetFileValue(memFs, "/config.yaml", "", "fallbacks", "gpt-4o-mini, anthropic/claude-3-5-haiku-latest"))
+	content, err = afero.ReadFile(memFs, "/config.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "fallbacks: [gpt-4o-mini, anthropic/claude-3-5-haiku-latest]\n")
+	req, _, err = Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Env: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o-mini", "anthropic/claude-3-5-haiku-latest"}, req.Fallbacks)
+	req, _, err = Load(Options{Fs: memFs, ConfigPath: "/config.yaml", ProjectPath: "/missing.yaml", Env: []string{"BOIL_FALLBACKS=gpt-4.1,gpt-4o"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4.1", "gpt-4o"}, req.Fallbacks)
+	problems, err := ValidateFile(memFs, "/config.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	assert.ErrorContains(t, SetFileValue(memFs, "/config.yaml", "", "readmee", "true"), `did you mean "readme"`)
+	assert.ErrorContains(t, SetFileValue(memFs, "/config.yaml", "", "repair_rounds", "many"), "an integer")
+}
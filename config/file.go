@@ -0,0 +1,475 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/santiagomed/boil/core"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an issue found validating the configuration
+type Problem struct {
+	// Path is the configuration file of the problem, if any
+	Path string
+	// Line is the line of the problem in Path, if known
+	Line    int
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Path != "" && p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", p.Path, p.Line, p.Message)
+	case p.Path != "":
+		return fmt.Sprintf("%s: %s", p.Path, p.Message)
+	default:
+		return p.Message
+	}
+}
+
+// IsKey reports whether key is a configuration key
+func IsKey(key string) bool {
+	for _, k := range Keys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isGroup reports whether key groups nested configuration keys, as models groups models.code
+func isGroup(key string) bool {
+	for _, k := range Keys() {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile checks a configuration file and its profiles for unknown keys and values of
+// the wrong type
+func ValidateFile(fs afero.Fs, path string) ([]Problem, error) {
+	doc, err := readFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	root := mappingNode(doc)
+	if root == nil {
+		if len(doc.Content) > 0 && doc.Content[0].Kind != yaml.MappingNode {
+			return []Problem{{Path: path, Line: doc.Content[0].Line, Message: "configuration must be a mapping of keys to values"}}, nil
+		}
+		return nil, nil
+	}
+	return validateMapping(path, root, "", ""), nil
+}
+
+// validateMapping checks the keys and values of a mapping of a configuration file. Profiles
+// are checked like the top-level mapping, with prefix naming the profile. Nested mappings
+// are checked with keyPrefix naming the group of their keys.
+func validateMapping(path string, mapping *yaml.Node, prefix, keyPrefix string) []Problem {
+	var problems []Problem
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		key := keyPrefix + keyNode.Value
+		name := prefix + key
+		problem := func(node *yaml.Node, format string, args ...interface{}) {
+			problems = append(problems, Problem{Path: path, Line: node.Line, Key: name, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if prefix == "" && keyPrefix == "" && key == DefaultProfileKey {
+			if valueNode.Kind != yaml.ScalarNode {
+				problem(valueNode, "invalid value for %s: expected a profile name", key)
+			}
+			continue
+		}
+		if prefix == "" && keyPrefix == "" && key == ProfilesKey {
+			if valueNode.Kind != yaml.MappingNode {
+				problem(valueNode, "invalid value for %s: expected a mapping of profile names to configuration values", key)
+				continue
+			}
+			for j := 0; j+1 < len(valueNode.Content); j += 2 {
+				profileNode, values := valueNode.Content[j], valueNode.Content[j+1]
+				if values.Kind != yaml.MappingNode {
+					problem(values, "invalid value for profile %q: expected a mapping of keys to values", profileNode.Value)
+					continue
+				}
+				problems = append(problems, validateMapping(path, values, fmt.Sprintf("%s.%s.", ProfilesKey, profileNode.Value), "")...)
+			}
+			continue
+		}
+		if isGroup(key) {
+			if valueNode.Kind != yaml.MappingNode {
+				problem(valueNode, "invalid value for %s: expected a mapping of keys to values", name)
+				continue
+			}
+			problems = append(problems, validateMapping(path, valueNode, prefix, key+".")...)
+			continue
+		}
+
+		if !IsKey(key) {
+			msg := fmt.Sprintf("unknown key %q", name)
+			if s := suggestKey(key); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", prefix+s)
+			}
+			problem(keyNode, "%s", msg)
+			continue
+		}
+
+		var value interface{}
+		if err := valueNode.Decode(&value); err != nil {
+			problem(valueNode, "invalid value for %s: %v", name, err)
+			continue
+		}
+		if err := checkValue(name, key, value); err != nil {
+			problem(valueNode, "%s", err.Error())
+		}
+	}
+	return problems
+}
+
+// ValidateRequest checks that the provider of the request is supported, that its models and
+// fallback models are known to work with their providers and that its rate limits are valid
+func ValidateRequest(req *core.Request) []Problem {
+	if !llm.IsKnownProvider(req.Provider) {
+		return []Problem{{Key: "provider", Message: fmt.Sprintf("unknown provider %q (supported providers: %s)", req.Provider, strings.Join(llm.Providers(), ", "))}}
+	}
+	models := map[string]string{"model_name": req.ModelName}
+	for _, kind := range []string{core.ModelPlanning, core.ModelCode, core.ModelDocs} {
+		models["models."+kind] = req.Model(kind)
+	}
+	var problems []Problem
+	for _, key := range Keys() {
+		model, ok := models[key]
+		if !ok || llm.IsKnownModel(req.Provider, model) {
+			continue
+		}
+		if key != "model_name" && model == req.ModelName {
+			// Reported once for model_name
+			continue
+		}
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf("model %q is not known for provider %s (known models: %s)", model, req.Provider, strings.Join(llm.Models(req.Provider), ", "))})
+	}
+	if req.RateLimit.RequestsPerMinute < 0 || req.RateLimit.TokensPerMinute < 0 {
+		problems = append(problems, Problem{Key: "rate_limit", Message: "rate limits can't be negative (use 0 for no limit)"})
+	}
+	for _, ref := range req.Fallbacks {
+		provider, model, err := llm.ParseModelRef(ref, req.Provider)
+		if err != nil {
+			problems = append(problems, Problem{Key: "fallbacks", Message: fmt.Sprintf("invalid fallback model: %v", err)})
+			continue
+		}
+		if !llm.IsKnownModel(provider, model) {
+			problems = append(problems, Problem{Key: "fallbacks", Message: fmt.Sprintf("fallback model %q is not known for provider %s (known models: %s)", model, provider, strings.Join(llm.Models(provider), ", "))})
+		}
+	}
+	return problems
+}
+
+// SetFileValue sets a configuration key in a configuration file, creating the file if needed.
+// The key is set in the given profile if profile isn't empty. The value is checked against
+// the key's type, and the rest of the file, comments included, is kept as is.
+func SetFileValue(fs afero.Fs, path, profile, key, value string) error {
+	if key == DefaultProfileKey && profile == "" {
+		return setFileNode(fs, path, []string{key}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+	}
+	if !IsKey(key) {
+		msg := fmt.Sprintf("unknown configuration key %q", key)
+		if s := suggestKey(key); s != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	if err := checkValue(key, key, value); err != nil {
+		return err
+	}
+
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if isStringKey(key) {
+		// Keep values such as "true" or "42" strings
+		valueNode.Tag = "!!str"
+	}
+	if isListKey(key) {
+		// Lists are given comma-separated
+		valueNode = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				valueNode.Content = append(valueNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+			}
+		}
+	}
+	keys := strings.Split(key, ".")
+	if profile != "" {
+		keys = append([]string{ProfilesKey, profile}, keys...)
+	}
+	return setFileNode(fs, path, keys, valueNode)
+}
+
+// setFileNode sets the value at a path of keys in a configuration file, creating the file and
+// the mappings of the path as needed
+func setFileNode(fs afero.Fs, path string, keys []string, valueNode *yaml.Node) error {
+	doc, err := readFile(fs, path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if doc == nil || len(doc.Content) == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	mapping := mappingNode(doc)
+	if mapping == nil {
+		return fmt.Errorf("error updating config file %s: configuration must be a mapping of keys to values", path)
+	}
+
+	for depth, key := range keys {
+		last := depth == len(keys)-1
+		var found *yaml.Node
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value != key {
+				continue
+			}
+			if last {
+				valueNode.LineComment = mapping.Content[i+1].LineComment
+				mapping.Content[i+1] = valueNode
+			}
+			found = mapping.Content[i+1]
+		}
+		if found == nil {
+			found = valueNode
+			if !last {
+				found = &yaml.Node{Kind: yaml.MappingNode}
+			}
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, found)
+		}
+		if last {
+			break
+		}
+		if found.Kind != yaml.MappingNode {
+			return fmt.Errorf("error updating config file %s: %s must be a mapping", path, strings.Join(keys[:depth+1], "."))
+		}
+		mapping = found
+	}
+	return writeFile(fs, path, doc)
+}
+
+// UnsetFileValue removes a configuration key from a configuration file, or from one of its
+// profiles if profile isn't empty, and reports whether it was set. A missing file is left
+// missing.
+func UnsetFileValue(fs afero.Fs, path, profile, key string) (bool, error) {
+	doc, err := readFile(fs, path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	mapping := mappingNode(doc)
+	if profile != "" {
+		mapping = childMapping(childMapping(mapping, ProfilesKey), profile)
+	}
+	keys := strings.Split(key, ".")
+	for _, group := range keys[:len(keys)-1] {
+		mapping = childMapping(mapping, group)
+	}
+	if mapping == nil {
+		return false, nil
+	}
+
+	removed := false
+	for i := 0; i+1 < len(mapping.Content); {
+		if mapping.Content[i].Value == keys[len(keys)-1] {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			removed = true
+			continue
+		}
+		i += 2
+	}
+	if !removed {
+		return false, nil
+	}
+	return true, writeFile(fs, path, doc)
+}
+
+// childMapping returns the mapping under a key of a mapping, or nil if there is none
+func childMapping(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key && mapping.Content[i+1].Kind == yaml.MappingNode {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// writeFile encodes a YAML configuration file
+func writeFile(fs afero.Fs, path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("error encoding config file %s: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("error encoding config file %s: %w", path, err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := fs.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating config directory: %w", err)
+		}
+	}
+	// The file may hold API keys
+	if err := afero.WriteFile(fs, path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// readFile parses a YAML configuration file
+func readFile(fs afero.Fs, path string) (*yaml.Node, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// mappingNode returns the top-level mapping of a YAML document, or nil if there is none
+func mappingNode(doc *yaml.Node) *yaml.Node {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// checkValue checks that value can be converted to the type of a configuration key. name is
+// the key as shown in errors.
+func checkValue(name, key string, value interface{}) error {
+	if _, ok := value.(map[string]interface{}); ok {
+		return fmt.Errorf("invalid value for %s: expected a single value, got a mapping", name)
+	}
+	if items, ok := value.([]interface{}); ok {
+		if !isListKey(key) {
+			return fmt.Errorf("invalid value for %s: expected a single value, got a list", name)
+		}
+		for _, item := range items {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("invalid value for %s: expected %s", name, typeName(key))
+			}
+		}
+	}
+	if err := Set(core.DefaultRequest(), nil, key, value, Origin{Layer: LayerUser}); err != nil {
+		return fmt.Errorf("invalid value for %s: expected %s", name, typeName(key))
+	}
+	return nil
+}
+
+// isStringKey reports whether a configuration key holds a string
+func isStringKey(key string) bool {
+	value, ok := Value(core.DefaultRequest(), key)
+	if !ok {
+		return false
+	}
+	return reflect.TypeOf(value).Kind() == reflect.String
+}
+
+// isListKey reports whether a configuration key holds a list of strings
+func isListKey(key string) bool {
+	value, ok := Value(core.DefaultRequest(), key)
+	if !ok {
+		return false
+	}
+	_, ok = value.([]string)
+	return ok
+}
+
+// typeName describes the type of a configuration key's values
+func typeName(key string) string {
+	value, _ := Value(core.DefaultRequest(), key)
+	switch value.(type) {
+	case bool:
+		return "true or false"
+	case int:
+		return "an integer"
+	case string:
+		return "a string"
+	case []string:
+		return "a list of strings"
+	default:
+		if reflect.TypeOf(value).String() == "time.Duration" {
+			return "a duration such as 30s or 5m"
+		}
+		return reflect.TypeOf(value).String()
+	}
+}
+
+// suggestKey returns the configuration key closest to an unknown key, if any is close enough
+func suggestKey(key string) string {
+	type candidate struct {
+		key      string
+		distance int
+	}
+	var candidates []candidate
+	for _, k := range Keys() {
+		if d := editDistance(key, k); d <= 3 {
+			candidates = append(candidates, candidate{k, d})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	return candidates[0].key
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
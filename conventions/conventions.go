@@ -0,0 +1,156 @@
+package conventions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/santiagomed/boil/verify"
+	"github.com/spf13/afero"
+)
+
+// DefaultPath is where conventions are looked up in the current directory when the
+// configuration does not name a file
+const DefaultPath = ".boil/conventions.md"
+
+var (
+	// headingPattern matches markdown headings, capturing their level and text
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	// fencePattern matches the lines that open or close markdown code blocks
+	fencePattern = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// languages maps the first word of a section heading to the stack it applies to
+var languages = map[string]verify.Stack{
+	"go":         verify.StackGo,
+	"golang":     verify.StackGo,
+	"node":       verify.StackNode,
+	"nodejs":     verify.StackNode,
+	"node.js":    verify.StackNode,
+	"javascript": verify.StackNode,
+	"typescript": verify.StackNode,
+	"js":         verify.StackNode,
+	"ts":         verify.StackNode,
+	"rust":       verify.StackRust,
+	"python":     verify.StackPython,
+	"py":         verify.StackPython,
+}
+
+// Conventions are the coding conventions generated projects should follow. General
+// conventions apply to every project; sections under a language heading, such as "## Go",
+// apply only to projects of that stack.
+type Conventions struct {
+	general  string
+	sections map[verify.Stack]string
+}
+
+// Parse splits a conventions markdown document into general and per-language conventions.
+// A language section extends to the next heading of the same or a higher level.
+func Parse(content string) *Conventions {
+	c := &Conventions{sections: make(map[verify.Stack]string)}
+	var general []string
+	// parts holds the sections of each language, which may appear more than once
+	parts := make(map[verify.Stack][][]string)
+
+	var current verify.Stack
+	level := 0
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+		} else if match := headingPattern.FindStringSubmatch(line); match != nil && !inFence {
+			if current != "" && len(match[1]) <= level {
+				current = ""
+			}
+			if current == "" {
+				if stack, ok := language(match[2]); ok {
+					current, level = stack, len(match[1])
+					parts[stack] = append(parts[stack], nil)
+					continue
+				}
+			}
+		}
+
+		if current != "" {
+			last := len(parts[current]) - 1
+			parts[current][last] = append(parts[current][last], line)
+		} else {
+			general = append(general, line)
+		}
+	}
+
+	c.general = strings.TrimSpace(strings.Join(general, "\n"))
+	for stack, sections := range parts {
+		var texts []string
+		for _, lines := range sections {
+			if text := strings.TrimSpace(strings.Join(lines, "\n")); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) > 0 {
+			c.sections[stack] = strings.Join(texts, "\n\n")
+		}
+	}
+	return c
+}
+
+// language returns the stack a heading is about, from its first word
+func language(heading string) (verify.Stack, bool) {
+	fields := strings.Fields(strings.ToLower(heading))
+	if len(fields) == 0 {
+		return "", false
+	}
+	stack, ok := languages[strings.Trim(fields[0], ":,/()")]
+	return stack, ok
+}
+
+// Load reads and parses a conventions file
+func Load(fs afero.Fs, path string) (*Conventions, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading conventions file: %w", err)
+	}
+	return Parse(string(content)), nil
+}
+
+// Find loads the conventions file named by the configuration, which may start with "~/"
+// for the home directory, or DefaultPath if it exists. It returns nil without an error if
+// there are no conventions.
+func Find(fs afero.Fs, path string) (*Conventions, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("error getting user home directory: %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	if path != "" {
+		return Load(fs, path)
+	}
+	if _, err := fs.Stat(DefaultPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error accessing conventions file: %w", err)
+	}
+	return Load(fs, DefaultPath)
+}
+
+// General returns the conventions that apply to every project. It is safe to call on nil
+// conventions.
+func (c *Conventions) General() string {
+	if c == nil {
+		return ""
+	}
+	return c.general
+}
+
+// For returns the conventions of a stack. It is safe to call on nil conventions.
+func (c *Conventions) For(stack verify.Stack) string {
+	if c == nil {
+		return ""
+	}
+	return c.sections[stack]
+}
@@ -0,0 +1,83 @@
+package conventions
+
+import (
+	"testing"
+
+	"github.com/santiagomed/boil/verify"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `# Conventions
+
+Keep functions short.
+
+## Layout
+
+Put commands in cmd/ and libraries in internal/.
+
+## Go
+
+Wrap errors with fmt.Errorf and %w.
+
+### Logging
+
+Use log/slog.
+
+` + "```go\n# not a heading\n```" + `
+
+## TypeScript
+
+Use ESM imports.
+
+## Python conventions
+
+Use type hints.
+
+## Testing
+
+Test every package.
+
+## Go
+
+Use table-driven tests.
+`
+
+func TestParse(t *testing.T) {
+	c := Parse(document)
+
+	assert.Equal(t, "# Conventions\n\nKeep functions short.\n\n## Layout\n\nPut commands in cmd/ and libraries in internal/.\n\n## Testing\n\nTest every package.", c.General())
+	assert.Equal(t, "Wrap errors with fmt.Errorf and %w.\n\n### Logging\n\nUse log/slog.\n\n```go\n# not a heading\n```\n\nUse table-driven tests.", c.For(verify.StackGo))
+	assert.Equal(t, "Use ESM imports.", c.For(verify.StackNode))
+	assert.Equal(t, "Use type hints.", c.For(verify.StackPython))
+	assert.Empty(t, c.For(verify.StackRust))
+
+	var none *Conventions
+	assert.Empty(t, none.General())
+	assert.Empty(t, none.For(verify.StackGo))
+}
+
+func TestFind(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+
+	c, err := Find(memFs, "")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = Find(memFs, "team.md")
+	assert.Error(t, err)
+
+	require.NoError(t, afero.WriteFile(memFs, DefaultPath, []byte("Use tabs.\n\n## Rust\n\nUse anyhow.\n"), 0644))
+	require.NoError(t, afero.WriteFile(memFs, "team.md", []byte("## Go\n\nUse zap.\n"), 0644))
+
+	c, err = Find(memFs, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Use tabs.", c.General())
+	assert.Equal(t, "Use anyhow.", c.For(verify.StackRust))
+
+	c, err = Find(memFs, "team.md")
+	require.NoError(t, err)
+	assert.Empty(t, c.General())
+	assert.Equal(t, "Use zap.", c.For(verify.StackGo))
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/santiagomed/boil/verify"
+)
+
+// AugmentOptions configures adding a project to an existing repository instead of
+// generating a new one
+type AugmentOptions struct {
+	// Existing is the existing repository, loaded into memory
+	Existing *fs.FileSystem
+	// Summary describes the existing repository in prompts
+	Summary string
+	// AllowOverwrite allows generating files that already exist in the repository
+	AllowOverwrite bool
+}
+
+// protects reports whether path is an existing file that must not be generated
+func (o *AugmentOptions) protects(path string) bool {
+	if o == nil || o.AllowOverwrite || o.Existing == nil {
+		return false
+	}
+	return o.Existing.Exists(path) && !o.Existing.IsDir(path)
+}
+
+// NewAugmentStepManager creates a step manager that adds a project to an existing repository.
+// Prompts include a summary of the repository, and files that already exist in it are not
+// generated unless overwriting is allowed.
+func NewAugmentStepManager(llm llm.LlmClient, fs *fs.FileSystem, opts *AugmentOptions) *DefaultStepManager {
+	return NewAugmentStepManagerWithClients(nil, llm, fs, opts)
+}
+
+// NewAugmentStepManagerWithClients creates an augment step manager whose steps use the client
+// given for them in clients, or else the fallback client
+func NewAugmentStepManagerWithClients(clients StepClients, fallback llm.LlmClient, fs *fs.FileSystem, opts *AugmentOptions) *DefaultStepManager {
+	sm := NewStepManagerWithClients(clients, fallback, fs)
+	sm.stepMap[GenerateProjectDetails] = &GenerateProjectDetailsStep{llm: clients.client(GenerateProjectDetails, fallback), augment: opts}
+	sm.stepMap[GenerateFileTree] = &GenerateFileTreeStep{llm: clients.client(GenerateFileTree, fallback), augment: opts}
+	sm.stepMap[ExecuteFileOperations] = &ExecuteFileOperationsStep{fs: fs, augment: opts}
+	sm.stepMap[GenerateFileContents] = &GenerateFileContentsStep{llm: clients.client(GenerateFileContents, fallback), fs: fs, augment: opts, validators: verify.NewValidators()}
+	sm.stepMap[CreateOptionalComponents] = &CreateOptionalComponentsStep{llm: clients.client(CreateOptionalComponents, fallback), fs: fs, augment: opts}
+	// Metadata describes a whole project, not a component added to a repository
+	sm.stepMap[Done] = &DoneStep{}
+	return sm
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/santiagomed/boil/fs"
+	"github.com/spf13/afero"
+)
+
+// MetadataPath is where generated projects store the metadata used to regenerate their files
+const MetadataPath = ".boil/project.json"
+
+// Metadata describes how a project was generated
+type Metadata struct {
+	Description string   `json:"description"`
+	Details     string   `json:"details"`
+	FileTree    string   `json:"file_tree"`
+	FileOrder   []string `json:"file_order"`
+	ModelName   string   `json:"model_name"`
+}
+
+// SaveMetadata writes the metadata of a generated project to MetadataPath
+func SaveMetadata(fs *fs.FileSystem, state *State) error {
+	metadata := Metadata{
+		Description: state.Request.ProjectDescription,
+		Details:     state.ProjectDetails,
+		FileTree:    state.FileTree,
+		FileOrder:   state.FileOrder,
+		ModelName:   state.Request.ModelName,
+	}
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding project metadata: %w", err)
+	}
+	return fs.WriteFile(MetadataPath, string(content)+"\n")
+}
+
+// LoadMetadata reads the metadata of a generated project from MetadataPath. It returns
+// nil without an error if the project has no metadata.
+func LoadMetadata(fs *fs.FileSystem) (*Metadata, error) {
+	content, err := afero.ReadFile(fs.Fs, MetadataPath)
+	if err != nil {
+		if fs.Exists(MetadataPath) {
+			return nil, fmt.Errorf("error reading project metadata: %w", err)
+		}
+		return nil, nil
+	}
+	var metadata Metadata
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("error parsing project metadata: %w", err)
+	}
+	return &metadata, nil
+}
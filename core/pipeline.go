@@ -24,6 +24,12 @@ const (
 	GenerateFileContents
 	CreateOptionalComponents
 	Done
+	VerifyProject
+	RepairProject
+	CheckProject
+
+	// numSteps is the number of step types. New step types go before it.
+	numSteps
 )
 
 type State struct {
@@ -34,20 +40,45 @@ type State struct {
 	PreviousFiles  map[string]string
 	Request        *Request
 	Logger         logger.Logger
+	Report         *Report
+
+	// publish sends events to the pipeline's publisher
+	publish func(event Event)
+	// ctx is the context the pipeline is executed with
+	ctx context.Context
+}
+
+// Context returns the context the pipeline is executed with, which is cancelled when the
+// pipeline is
+func (s *State) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// PublishEvent publishes an event if the pipeline's publisher accepts events
+func (s *State) PublishEvent(event Event) {
+	if s.publish != nil {
+		s.publish(event)
+	}
 }
 
 type Pipeline struct {
 	stepManager StepManager
 	state       *State
 	publisher   StepPublisher
+	// current is the step being executed
+	current StepType
 }
 
-func NewPipeline(r *Request, sm StepManager, pub StepPublisher, logger logger.Logger) (*Pipeline, error) {
+func NewPipeline(r *Request, sm StepManager, pub StepPublisher, l logger.Logger) (*Pipeline, error) {
 	return &Pipeline{
 		state: &State{
 			Request:       r,
 			PreviousFiles: make(map[string]string),
-			Logger:        logger,
+			Logger:        logger.NewRedactingLogger(l, r.Secrets()...),
+			Report:        &Report{},
 		},
 		publisher:   pub,
 		stepManager: sm,
@@ -56,6 +87,11 @@ func NewPipeline(r *Request, sm StepManager, pub StepPublisher, logger logger.Lo
 
 func (p *Pipeline) Execute(ctx context.Context) error {
 	steps := p.stepManager.GetSteps()
+	if p.state.Report == nil {
+		p.state.Report = &Report{}
+	}
+	p.state.publish = p.publishEvent
+	p.state.ctx = ctx
 	p.state.Logger.Info("Starting pipeline execution")
 	for i, stepType := range steps {
 		select {
@@ -64,6 +100,7 @@ func (p *Pipeline) Execute(ctx context.Context) error {
 			return context.Canceled
 		default:
 			p.state.Logger.Info(fmt.Sprintf("Attempting to execute step %d: %v", i, stepType))
+			p.current = stepType
 			step := p.stepManager.GetStep(stepType)
 			if step == nil {
 				p.state.Logger.Error(fmt.Sprintf("Step %v not found", stepType))
@@ -91,11 +128,58 @@ func (p *Pipeline) Execute(ctx context.Context) error {
 	return nil
 }
 
+// publishEvent sends an event to the publisher if it implements EventPublisher
+func (p *Pipeline) publishEvent(event Event) {
+	if pub, ok := p.publisher.(EventPublisher); ok {
+		pub.PublishEvent(event)
+	}
+}
+
+// PublishEvent publishes an event of the step being executed, for events that happen outside
+// of the steps, such as waiting for a rate limit. It must be called from the goroutine
+// executing the pipeline.
+func (p *Pipeline) PublishEvent(event Event) {
+	event.Step = p.current
+	p.publishEvent(event)
+}
+
+// Report returns the report of the pipeline run
+func (p *Pipeline) Report() *Report {
+	return p.state.Report
+}
+
 type StepPublisher interface {
 	PublishStep(step StepType)
 	Error(step StepType, err error)
 }
 
+// EventType identifies the kind of an Event
+type EventType string
+
+const (
+	// EventVerification reports whether the generated project passed verification
+	EventVerification EventType = "verification"
+	// EventRepair reports the files regenerated in a round of repairing the generated project
+	EventRepair EventType = "repair"
+	// EventCheck reports the issues found by static checks of the generated project
+	EventCheck EventType = "check"
+	// EventRateLimit reports that a completion waited for the rate limit of its API key
+	EventRateLimit EventType = "rate_limit"
+)
+
+// Event is something notable that happened during a step, other than its completion
+type Event struct {
+	Step    StepType
+	Type    EventType
+	Success bool
+	Message string
+}
+
+// EventPublisher is implemented by publishers that also receive events
+type EventPublisher interface {
+	PublishEvent(event Event)
+}
+
 type DefaultStepPublisher struct{}
 
 func (p *DefaultStepPublisher) PublishStep(step StepType) {}
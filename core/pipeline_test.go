@@ -2,11 +2,17 @@ package core
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/santiagomed/boil/conventions"
 	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
 	"github.com/santiagomed/boil/logger"
+	"github.com/santiagomed/boil/verify"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -166,6 +172,462 @@ func TestPipeline_Execute(t *testing.T) {
 	assert.Equal(t, expectedStructure, structure)
 }
 
+func TestPipeline_Augment(t *testing.T) {
+	mockLLM := new(MockLLM)
+
+	existing := fs.NewMemoryFileSystem()
+	assert.NoError(t, existing.WriteFile("package.json", `{"name": "repo"}`))
+
+	operations := `{"operations": [
+		{"operation": "CREATE_FILE", "path": "package.json"},
+		{"operation": "CREATE_DIR", "path": "services/billing"},
+		{"operation": "CREATE_FILE", "path": "services/billing/index.js"}
+	]}`
+	order := `{"files": ["package.json", "services/billing/index.js"]}`
+
+	mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+		return strings.Contains(prompt, "summary of an existing repository")
+	}), "text").Return("Details", nil).Twice()
+	mockLLM.On("GetCompletion", mock.AnythingOfType("string"), "json_object").Return(operations, nil).Once()
+	mockLLM.On("GetCompletion", mock.AnythingOfType("string"), "json_object").Return(order, nil).Once()
+	mockLLM.On("GetCompletion", mock.AnythingOfType("string"), "text").Return("module.exports = {}", nil).Once()
+
+	r := &Request{
+		ProjectDescription: "billing service",
+		ProjectName:        "billing",
+	}
+
+	memFS := fs.NewMemoryFileSystem()
+	sm := NewAugmentStepManager(mockLLM, memFS, &AugmentOptions{
+		Existing: existing,
+		Summary:  "project-root/\n└── package.json\n",
+	})
+	pipeline, err := NewPipeline(r, sm, &DefaultStepPublisher{}, logger.NewNullLogger())
+	assert.NoError(t, err)
+
+	err = pipeline.Execute(context.Background())
+	assert.NoError(t, err)
+	mockLLM.AssertExpectations(t)
+
+	structure, err := memFS.ListFiles(".")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"services": map[string]interface{}{
+			"billing": map[string]interface{}{"index.js": nil},
+		},
+	}, structure)
+}
+
+func TestRegenerateFile(t *testing.T) {
+	project := fs.NewMemoryFileSystem()
+	assert.NoError(t, project.WriteFile("main.go", "package main\n\nimport \"database/sql\""))
+	assert.NoError(t, project.WriteFile("go.mod", "module example"))
+
+	t.Run("with metadata", func(t *testing.T) {
+		mockLLM := new(MockLLM)
+		state := &State{
+			Request:        &Request{ProjectDescription: "api", ModelName: "test-model"},
+			ProjectDetails: "Saved details",
+			FileTree:       "Saved tree",
+			FileOrder:      []string{"go.mod", "main.go"},
+		}
+		assert.NoError(t, SaveMetadata(project, state))
+
+		mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "Saved details") &&
+				strings.Contains(prompt, "Saved tree") &&
+				strings.Contains(prompt, "// file: go.mod") &&
+				!strings.Contains(prompt, "// file: main.go") &&
+				strings.Contains(prompt, `import "database/sql"`) &&
+				strings.Contains(prompt, "use pgx")
+		}), "text").Return("package main", nil).Once()
+
+		content, err := RegenerateFile(mockLLM, project, "main.go", "use pgx", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", content)
+		mockLLM.AssertExpectations(t)
+		assert.NoError(t, project.Fs.RemoveAll(".boil"))
+	})
+
+	t.Run("without metadata", func(t *testing.T) {
+		mockLLM := new(MockLLM)
+		mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "summary of an existing project")
+		}), "text").Return("Derived details", nil).Once()
+		mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+			return strings.Contains(prompt, "Derived details") && strings.Contains(prompt, "project-root/")
+		}), "text").Return("package main", nil).Once()
+
+		content, err := RegenerateFile(mockLLM, project, "main.go", "use pgx", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", content)
+		mockLLM.AssertExpectations(t)
+	})
+
+	_, err := RegenerateFile(new(MockLLM), project, "missing.go", "", nil)
+	assert.Error(t, err)
+}
+
+// eventPublisher records the events published by a pipeline
+type eventPublisher struct {
+	DefaultStepPublisher
+	events []Event
+}
+
+func (p *eventPublisher) PublishEvent(event Event) {
+	p.events = append(p.events, event)
+}
+
+func TestPipeline_Verify(t *testing.T) {
+	sm := NewDefaultStepManager(new(MockLLM), fs.NewMemoryFileSystem())
+	sm.InsertStepAfter(GenerateFileContents, VerifyProject)
+	assert.Equal(t, []StepType{
+		GenerateProjectDetails,
+		GenerateFileTree,
+		GenerateFileOperations,
+		ExecuteFileOperations,
+		DetermineFileOrder,
+		GenerateFileContents,
+		VerifyProject,
+		CreateOptionalComponents,
+		Done,
+	}, sm.GetSteps())
+
+	tests := []struct {
+		name    string
+		command string
+		success bool
+	}{
+		{"passing", "test -f go.mod", true},
+		{"failing", "echo 'main.go:3: undefined: x'; exit 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memFS := fs.NewMemoryFileSystem()
+			assert.NoError(t, memFS.WriteFile("go.mod", "module example"))
+			sm := &DefaultStepManager{
+				stepMap: map[StepType]Step{
+					VerifyProject: &VerifyProjectStep{fs: memFS},
+					Done:          &DoneStep{fs: memFS},
+				},
+				steps: []StepType{VerifyProject, Done},
+			}
+			r := &Request{Verify: true, VerifyCommand: tt.command, SaveMetadata: true}
+			pub := &eventPublisher{}
+			pipeline, err := NewPipeline(r, sm, pub, logger.NewNullLogger())
+			assert.NoError(t, err)
+
+			// A failed verification is reported, not returned as an error
+			assert.NoError(t, pipeline.Execute(context.Background()))
+
+			result := pipeline.Report().Verification
+			assert.NotNil(t, result)
+			assert.Equal(t, tt.success, result.Passed)
+			assert.Equal(t, verify.StackGo, result.Stack)
+			assert.Equal(t, tt.command, result.Command)
+
+			assert.Len(t, pub.events, 1)
+			assert.Equal(t, EventVerification, pub.events[0].Type)
+			assert.Equal(t, tt.success, pub.events[0].Success)
+
+			content, err := afero.ReadFile(memFS.Fs, ReportPath)
+			assert.NoError(t, err)
+			assert.Contains(t, string(content), `"passed": `+fmt.Sprint(tt.success))
+		})
+	}
+}
+
+func TestPipeline_VerifyCancel(t *testing.T) {
+	memFS := fs.NewMemoryFileSystem()
+	assert.NoError(t, memFS.WriteFile("go.mod", "module example"))
+	sm := &DefaultStepManager{
+		stepMap: map[StepType]Step{
+			VerifyProject: &VerifyProjectStep{fs: memFS},
+			Done:          &DoneStep{fs: memFS},
+		},
+		steps: []StepType{VerifyProject, Done},
+	}
+	r := &Request{Verify: true, VerifyCommand: "sleep 10", VerifyTimeout: time.Minute}
+	pipeline, err := NewPipeline(r, sm, &eventPublisher{}, logger.NewNullLogger())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = pipeline.Execute(ctx)
+
+	// The build is stopped with the pipeline, and no verification is reported
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Nil(t, pipeline.Report().Verification)
+}
+
+func TestRepairProjectStep(t *testing.T) {
+	command := `grep -q broken main.go && { echo "main.go:1:1: broken code"; exit 1; }; exit 0`
+
+	tests := []struct {
+		name     string
+		repaired []string
+		rounds   int
+		passed   bool
+	}{
+		{"fixed in first round", []string{"fixed"}, 3, true},
+		{"rounds exhausted", []string{"still broken", "still broken"}, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLLM := new(MockLLM)
+			for _, content := range tt.repaired {
+				mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+					return strings.Contains(prompt, "main.go:1:1: broken code") &&
+						strings.Contains(prompt, "// file: go.mod")
+				}), "text").Return(content, nil).Once()
+			}
+
+			memFS := fs.NewMemoryFileSystem()
+			assert.NoError(t, memFS.WriteFile("go.mod", "module example"))
+			assert.NoError(t, memFS.WriteFile("main.go", "broken"))
+			pub := &eventPublisher{}
+			state := &State{
+				Request: &Request{Repair: true, RepairRounds: tt.rounds, VerifyCommand: command},
+				PreviousFiles: map[string]string{
+					"go.mod":  "module example",
+					"main.go": "broken",
+				},
+				Logger:  logger.NewNullLogger(),
+				Report:  &Report{},
+				publish: pub.PublishEvent,
+			}
+
+			step := &RepairProjectStep{llm: mockLLM, fs: memFS}
+			assert.NoError(t, step.Execute(state))
+			mockLLM.AssertExpectations(t)
+
+			assert.Len(t, state.Report.Repairs, len(tt.repaired))
+			for i, round := range state.Report.Repairs {
+				assert.Equal(t, i+1, round.Round)
+				assert.Equal(t, []string{"main.go"}, round.Files)
+				assert.False(t, round.Build.Passed)
+			}
+			assert.Equal(t, tt.passed, state.Report.Verification.Passed)
+
+			content, err := afero.ReadFile(memFS.Fs, "main.go")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.repaired[len(tt.repaired)-1], string(content))
+
+			last := pub.events[len(pub.events)-1]
+			assert.Equal(t, EventVerification, last.Type)
+			assert.Equal(t, tt.passed, last.Success)
+			assert.Len(t, pub.events, len(tt.repaired)+1)
+		})
+	}
+}
+
+func TestCheckProjectStep(t *testing.T) {
+	assert.Equal(t, []StepType{CheckProject, RepairProject}, OptionalSteps(&Request{StaticChecks: true, Verify: true, Repair: true}))
+	assert.Equal(t, []StepType{VerifyProject}, OptionalSteps(&Request{Verify: true}))
+	assert.Empty(t, OptionalSteps(&Request{}))
+
+	memFS := fs.NewMemoryFileSystem()
+	assert.NoError(t, memFS.WriteFile("go.mod", "module example"))
+	assert.NoError(t, memFS.WriteFile("main.go", "package main\n\nimport \"example/missing\"\n\nfunc main() { missing.Run() }\n"))
+	pub := &eventPublisher{}
+	state := &State{
+		Request: &Request{StaticChecks: true},
+		Logger:  logger.NewNullLogger(),
+		Report:  &Report{},
+		publish: pub.PublishEvent,
+	}
+
+	step := &CheckProjectStep{fs: memFS}
+	assert.NoError(t, step.Execute(state))
+	assert.Len(t, state.Report.Issues, 1)
+	assert.Equal(t, "main.go", state.Report.Issues[0].File)
+	assert.Len(t, pub.events, 1)
+	assert.Equal(t, EventCheck, pub.events[0].Type)
+	assert.Contains(t, pub.events[0].Message, "the project has no package missing")
+
+	// A project without issues publishes nothing
+	assert.NoError(t, memFS.WriteFile("main.go", "package main\n\nfunc main() {}\n"))
+	pub.events = nil
+	state.Report = &Report{}
+	assert.NoError(t, step.Execute(state))
+	assert.Empty(t, state.Report.Issues)
+	assert.Empty(t, pub.events)
+}
+
+func TestGenerateFileContentsStep_Validate(t *testing.T) {
+	mockLLM := new(MockLLM)
+	mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+		return !strings.Contains(prompt, "It is not valid")
+	}), "text").Return("```yaml\nserver:\n  port: 8080\n ports: [1]\n```", nil).Once()
+	mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+		return strings.Contains(prompt, "It is not valid: invalid YAML")
+	}), "text").Return("```yaml\nserver:\n  port: 8080\n```", nil).Once()
+	mockLLM.On("GetCompletion", mock.AnythingOfType("string"), "json_object").Return("```json\n{\"name\": \"app\"}\n```", nil).Once()
+
+	memFS := fs.NewMemoryFileSystem()
+	state := &State{
+		Request:       &Request{},
+		FileOrder:     []string{"config.yaml", "package.json"},
+		PreviousFiles: make(map[string]string),
+		Logger:        logger.NewNullLogger(),
+		Report:        &Report{},
+	}
+	step := &GenerateFileContentsStep{llm: mockLLM, fs: memFS, validators: verify.NewValidators()}
+	assert.NoError(t, step.Execute(state))
+	mockLLM.AssertExpectations(t)
+
+	content, err := afero.ReadFile(memFS.Fs, "config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "server:\n  port: 8080\n", string(content))
+	content, err = afero.ReadFile(memFS.Fs, "package.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"name\": \"app\"}\n", string(content))
+	assert.Empty(t, state.Report.Issues)
+}
+
+func TestGenerateFileTreeStep_Pinned(t *testing.T) {
+	mockLLM := new(MockLLM)
+	mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+		return strings.Contains(prompt, "A billing service") && strings.Contains(prompt, "Additional instructions:\nUse gRPC")
+	}), "text").Return("Details", nil).Once()
+
+	state := &State{
+		Request: &Request{ProjectDescription: "A billing service", Instructions: "Use gRPC", FileTree: "billing/\n└── main.go\n"},
+		Logger:  logger.NewNullLogger(),
+	}
+	assert.NoError(t, (&GenerateProjectDetailsStep{llm: mockLLM}).Execute(state))
+	assert.NoError(t, (&GenerateFileTreeStep{llm: mockLLM}).Execute(state))
+	mockLLM.AssertExpectations(t)
+	assert.Equal(t, "Details", state.ProjectDetails)
+	assert.Equal(t, "billing/\n└── main.go\n", state.FileTree)
+}
+
+func TestGenerateFileContentsStep_Conventions(t *testing.T) {
+	mockLLM := new(MockLLM)
+	mockLLM.On("GetCompletion", mock.MatchedBy(func(prompt string) bool {
+		return strings.Contains(prompt, "Use log/slog.") && !strings.Contains(prompt, "Use ESM imports.")
+	}), "text").Return("package main\n", nil).Once()
+
+	memFS := fs.NewMemoryFileSystem()
+	assert.NoError(t, memFS.WriteFile("go.mod", "module example\n"))
+	state := &State{
+		Request:       &Request{Conventions: conventions.Parse("## Go\n\nUse log/slog.\n\n## Node\n\nUse ESM imports.\n")},
+		FileOrder:     []string{"main.go"},
+		PreviousFiles: make(map[string]string),
+		Logger:        logger.NewNullLogger(),
+		Report:        &Report{},
+	}
+	step := &GenerateFileContentsStep{llm: mockLLM, fs: memFS}
+	assert.NoError(t, step.Execute(state))
+	mockLLM.AssertExpectations(t)
+}
+
+func TestDoneStep_RedactsReport(t *testing.T) {
+	memFS := fs.NewMemoryFileSystem()
+	state := &State{
+		Request: &Request{SaveMetadata: true, APIKey: "sk-secret-key"},
+		Logger:  logger.NewNullLogger(),
+		Report: &Report{
+			Verification: &verify.Result{Command: "go test ./...", Output: "client_test.go:12: using key sk-secret-key"},
+			Issues:       []verify.Issue{{File: "main.go", Message: `unused constant "sk-secret-key"`}},
+		},
+	}
+	step := &DoneStep{fs: memFS}
+	assert.NoError(t, step.Execute(state))
+
+	content, err := afero.ReadFile(memFS.Fs, ReportPath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "sk-secret-key")
+	assert.Contains(t, string(content), "using key "+logger.Redacted)
+}
+
+func TestStepManagerWithClients(t *testing.T) {
+	planning := new(MockLLM)
+	planning.On("GetCompletion", mock.AnythingOfType("string"), "json_object").Return(`{"files": ["main.go"]}`, nil).Once()
+	code := new(MockLLM)
+	code.On("GetCompletion", mock.AnythingOfType("string"), "text").Return("package main\n", nil).Once()
+
+	clients, err := NewStepClients(func(kind string) (llm.LlmClient, error) {
+		switch kind {
+		case ModelPlanning:
+			return planning, nil
+		case ModelCode:
+			return code, nil
+		default:
+			return nil, nil
+		}
+	})
+	assert.NoError(t, err)
+	for step := StepType(0); step < numSteps; step++ {
+		if ModelKind(step) != "" {
+			assert.Contains(t, clients, step, "every step that uses a model has a client")
+		}
+	}
+	fallback := new(MockLLM)
+	memFS := fs.NewMemoryFileSystem()
+	sm := NewStepManagerWithClients(clients, fallback, memFS)
+
+	state := &State{
+		Request:       &Request{},
+		FileTree:      "main.go",
+		PreviousFiles: make(map[string]string),
+		Logger:        logger.NewNullLogger(),
+	}
+	assert.NoError(t, sm.GetStep(DetermineFileOrder).Execute(state))
+	assert.NoError(t, sm.GetStep(GenerateFileContents).Execute(state))
+	assert.Equal(t, &CreateOptionalComponentsStep{llm: fallback, fs: memFS}, sm.GetStep(CreateOptionalComponents), "steps without a client use the fallback")
+	planning.AssertExpectations(t)
+	code.AssertExpectations(t)
+	fallback.AssertNotCalled(t, "GetCompletion", mock.Anything, mock.Anything)
+}
+
+func TestDoneStep_ReportsUsage(t *testing.T) {
+	memFS := fs.NewMemoryFileSystem()
+	usage := llm.NewUsageTracker()
+	usage.Record(ModelCode, "gpt-4o", 2000, 1000)
+	state := &State{
+		Request: &Request{SaveMetadata: true, Usage: usage},
+		Logger:  logger.NewNullLogger(),
+		Report:  &Report{},
+	}
+	assert.NoError(t, (&DoneStep{fs: memFS}).Execute(state))
+
+	content, err := afero.ReadFile(memFS.Fs, ReportPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"kind": "code"`)
+	assert.Contains(t, string(content), `"cost_usd": 0.015`)
+}
+
+// stepFunc is a step that runs a function
+type stepFunc func(state *State) error
+
+func (f stepFunc) Execute(state *State) error {
+	return f(state)
+}
+
+func TestPipeline_PublishEvent(t *testing.T) {
+	pub := &eventPublisher{}
+	var pipeline *Pipeline
+	sm := &DefaultStepManager{
+		stepMap: map[StepType]Step{
+			GenerateFileTree: stepFunc(func(state *State) error {
+				// As a client's rate limiter does while a step waits for a completion
+				pipeline.PublishEvent(Event{Type: EventRateLimit, Success: true, Message: "Waited 2s"})
+				return nil
+			}),
+		},
+		steps: []StepType{GenerateFileTree},
+	}
+	pipeline, err := NewPipeline(&Request{}, sm, pub, logger.NewNullLogger())
+	assert.NoError(t, err)
+	assert.NoError(t, pipeline.Execute(context.Background()))
+	assert.Equal(t, []Event{{Step: GenerateFileTree, Type: EventRateLimit, Success: true, Message: "Waited 2s"}}, pub.events)
+}
+
 func TestPipeline_Cancel(t *testing.T) {
 	mockLLM := new(MockLLM)
 
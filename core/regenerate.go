@@ -0,0 +1,121 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/santiagomed/boil/conventions"
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/spf13/afero"
+)
+
+const (
+	// maxRegenerateContext is the total size of the other files included as context when regenerating a file
+	maxRegenerateContext = 100000
+	// maxRegenerateTreeEntries is the number of file tree entries used when a project has no metadata
+	maxRegenerateTreeEntries = 300
+)
+
+// RegenerateFile generates new content for a single file of an existing project, following
+// the given hint and conventions. It reuses the project details and file tree saved in the
+// project metadata, or derives them from the project itself if it has none.
+func RegenerateFile(client llm.LlmClient, project *fs.FileSystem, path, hint string, conv *conventions.Conventions) (string, error) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	current, err := afero.ReadFile(project.Fs, filepath.FromSlash(path))
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	metadata, err := LoadMetadata(project)
+	if err != nil {
+		return "", err
+	}
+	if metadata == nil {
+		metadata, err = deriveMetadata(client, project)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	previousFiles, err := regenerateContext(project, path, metadata.FileOrder)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := llm.RegenerateFileContent(client, path, metadata.Details, metadata.FileTree, previousFiles, stackConventions(conv, project), string(current), hint)
+	if err != nil {
+		return "", fmt.Errorf("failed to regenerate %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// deriveMetadata describes a project without saved metadata from its files
+func deriveMetadata(client llm.LlmClient, project *fs.FileSystem) (*Metadata, error) {
+	summary, err := project.Summarize(maxRegenerateTreeEntries)
+	if err != nil {
+		return nil, err
+	}
+	details, err := llm.DescribeExistingProject(client, summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe project: %w", err)
+	}
+	tree, err := project.Tree(maxRegenerateTreeEntries)
+	if err != nil {
+		return nil, err
+	}
+	return &Metadata{Details: details, FileTree: tree}, nil
+}
+
+// regenerateContext collects the contents of the other files of a project, in generation
+// order when known, up to maxRegenerateContext bytes in total
+func regenerateContext(project *fs.FileSystem, path string, order []string) (map[string]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, file := range order {
+		if project.Exists(file) && !project.IsDir(file) {
+			files = append(files, file)
+			seen[file] = true
+		}
+	}
+	err := afero.Walk(project.Fs, ".", func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(p)
+		if info.IsDir() {
+			if slashPath == filepath.Dir(MetadataPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !seen[slashPath] {
+			files = append(files, slashPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing project files: %w", err)
+	}
+
+	previousFiles := make(map[string]string)
+	size := 0
+	for _, file := range files {
+		if file == path || strings.HasPrefix(file, filepath.Dir(MetadataPath)+"/") {
+			continue
+		}
+		content, err := afero.ReadFile(project.Fs, filepath.FromSlash(file))
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", file, err)
+		}
+		if bytes.IndexByte(content, 0) >= 0 || size+len(content) > maxRegenerateContext {
+			continue
+		}
+		previousFiles[file] = string(content)
+		size += len(content)
+	}
+	return previousFiles, nil
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/santiagomed/boil/fs"
+	"github.com/santiagomed/boil/llm"
+	"github.com/santiagomed/boil/logger"
+	"github.com/santiagomed/boil/verify"
+)
+
+// ReportPath is where generated projects store the report of the run that generated them
+const ReportPath = ".boil/report.json"
+
+// Report records what happened during a pipeline run, beyond the generated files
+type Report struct {
+	// Verification is the result of building the generated project, if it was verified
+	Verification *verify.Result `json:"verification,omitempty"`
+	// Repairs lists the rounds of regenerating files that failed to build, in order
+	Repairs []RepairRound `json:"repairs,omitempty"`
+	// Issues lists the problems found by static checks of the generated files
+	Issues []verify.Issue `json:"issues,omitempty"`
+	// Usage lists the tokens used and their estimated cost, by kind of step and model
+	Usage []llm.Usage `json:"usage,omitempty"`
+}
+
+// RepairRound records one round of repairing a generated project
+type RepairRound struct {
+	Round int `json:"round"`
+	// Build is the failed build whose errors were fed back to the LLM
+	Build *verify.Result `json:"build"`
+	// Files lists the regenerated files
+	Files []string `json:"files"`
+}
+
+// empty reports whether nothing was recorded in the report
+func (r *Report) empty() bool {
+	return r == nil || (r.Verification == nil && len(r.Repairs) == 0 && len(r.Issues) == 0 && len(r.Usage) == 0)
+}
+
+// Redact removes secrets from the build output and messages of the report
+func (r *Report) Redact(secrets ...string) {
+	redactResult := func(result *verify.Result) {
+		if result != nil {
+			result.Command = logger.Redact(result.Command, secrets...)
+			result.Output = logger.Redact(result.Output, secrets...)
+		}
+	}
+	redactResult(r.Verification)
+	for _, round := range r.Repairs {
+		redactResult(round.Build)
+	}
+	for i := range r.Issues {
+		r.Issues[i].Message = logger.Redact(r.Issues[i].Message, secrets...)
+	}
+}
+
+// SaveReport writes a run report to ReportPath
+func SaveReport(fs *fs.FileSystem, report *Report) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding run report: %w", err)
+	}
+	return fs.WriteFile(ReportPath, string(content)+"\n")
+}
@@ -1,6 +1,13 @@
 package core
 
-import "os"
+import (
+	"os"
+	"time"
+
+	"github.com/santiagomed/boil/conventions"
+	"github.com/santiagomed/boil/llm"
+	"github.com/santiagomed/boil/verify"
+)
 
 // Request indicates the user's request for a new project.
 type Request struct {
@@ -10,9 +17,70 @@ type Request struct {
 	GitIgnore          bool   `mapstructure:"git_ignore"`
 	Readme             bool   `mapstructure:"readme"`
 	Dockerfile         bool   `mapstructure:"dockerfile"`
+	OutputDir          string `mapstructure:"output_dir"`
+	SaveMetadata       bool   `mapstructure:"save_metadata"`
+
+	// StaticChecks checks the generated Go files for errors without a toolchain
+	StaticChecks bool `mapstructure:"static_checks"`
+	// Verify builds the generated project before writing it, with VerifyCommand or a
+	// command inferred from the project's manifest files
+	Verify        bool          `mapstructure:"verify"`
+	VerifyCommand string        `mapstructure:"verify_command"`
+	VerifyTimeout time.Duration `mapstructure:"verify_timeout"`
+	// Repair regenerates files that fail to build, with the build errors as context, for
+	// up to RepairRounds rounds. It implies Verify.
+	Repair       bool `mapstructure:"repair"`
+	RepairRounds int  `mapstructure:"repair_rounds"`
+
+	// Instructions are extra requirements added to the project description
+	Instructions string `mapstructure:"instructions"`
+	// FileTree pins the file tree of the project instead of generating it
+	FileTree string `mapstructure:"file_tree"`
+	// ConventionsFile is a markdown file of coding conventions to follow, loaded into
+	// Conventions. Defaults to conventions.DefaultPath if it exists.
+	ConventionsFile string                   `mapstructure:"conventions"`
+	Conventions     *conventions.Conventions `mapstructure:"-"`
+
+	// Provider is the LLM API used: openai or anthropic
+	Provider        string `mapstructure:"provider"`
+	APIKey          string `mapstructure:"openai_api_key"`
+	AnthropicAPIKey string `mapstructure:"anthropic_api_key"`
+	ModelName       string `mapstructure:"model_name"`
+	// Models overrides ModelName for some kinds of steps
+	Models StepModels `mapstructure:"models"`
+	// Fallbacks are the models tried in order when a completion fails, as provider/model or
+	// as a model of Provider
+	Fallbacks []string `mapstructure:"fallbacks"`
+	// RateLimit limits the completions sent with each API key. The limits are shared by the
+	// pipelines of an engine.
+	RateLimit RateLimit `mapstructure:"rate_limit"`
+
+	// Usage records the tokens used by the request's completions, if set
+	Usage *llm.UsageTracker `mapstructure:"-"`
+}
+
+// Kinds of steps that can use their own model
+const (
+	// ModelPlanning generates the project details, file tree, file operations and file order
+	ModelPlanning = "planning"
+	// ModelCode generates, repairs and regenerates source files
+	ModelCode = "code"
+	// ModelDocs generates the README, Dockerfile and .gitignore
+	ModelDocs = "docs"
+)
 
-	APIKey    string `mapstructure:"openai_api_key"`
-	ModelName string `mapstructure:"model_name"`
+// StepModels are the models of each kind of step. Empty models default to the request's
+// ModelName.
+type StepModels struct {
+	Planning string `mapstructure:"planning"`
+	Code     string `mapstructure:"code"`
+	Docs     string `mapstructure:"docs"`
+}
+
+// RateLimit is a limit of requests and tokens per minute. A limit of 0 disables it.
+type RateLimit struct {
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	TokensPerMinute   int `mapstructure:"tokens_per_minute"`
 }
 
 // DefaultRequest returns a Request with default values.
@@ -20,12 +88,19 @@ func DefaultRequest() *Request {
 	return &Request{
 		ProjectDescription: "Simple go 'Hello World' web app",
 		ProjectName:        "my-project",
+		Provider:           llm.ProviderOpenAI,
 		APIKey:             os.Getenv("OPENAI_API_KEY"),
+		AnthropicAPIKey:    os.Getenv("ANTHROPIC_API_KEY"),
 		ModelName:          "gpt-4o-mini",
 		GitRepo:            false,
 		GitIgnore:          false,
 		Readme:             false,
 		Dockerfile:         false,
+		OutputDir:          ".",
+		SaveMetadata:       true,
+		VerifyTimeout:      verify.DefaultTimeout,
+		RepairRounds:       3,
+		StaticChecks:       true,
 	}
 }
 
@@ -41,3 +116,79 @@ func NewRequest(projectDescription, projectName, apiKey, modelName string, gitRe
 		Dockerfile:         dockerfile,
 	}
 }
+
+// Clone returns a copy of the request that shares no lists with it, so that values can be
+// applied to the copy without changing the original. The conventions and usage tracker are
+// shared.
+func (r *Request) Clone() *Request {
+	c := *r
+	c.Fallbacks = append([]string(nil), r.Fallbacks...)
+	return &c
+}
+
+// ProviderAPIKey returns the API key of the request's provider
+func (r *Request) ProviderAPIKey() string {
+	return r.APIKeyFor(r.Provider)
+}
+
+// APIKeyFor returns the API key of a provider
+func (r *Request) APIKeyFor(provider string) string {
+	if provider == llm.ProviderAnthropic {
+		return r.AnthropicAPIKey
+	}
+	return r.APIKey
+}
+
+// Secrets returns the API keys of the request, which must not be logged or reported
+func (r *Request) Secrets() []string {
+	return []string{r.APIKey, r.AnthropicAPIKey}
+}
+
+// ModelKind returns the kind of model a step uses, or "" if the step doesn't use an LLM
+func ModelKind(step StepType) string {
+	switch step {
+	case GenerateProjectDetails, GenerateFileTree, GenerateFileOperations, DetermineFileOrder:
+		return ModelPlanning
+	case GenerateFileContents, RepairProject:
+		return ModelCode
+	case CreateOptionalComponents:
+		return ModelDocs
+	default:
+		return ""
+	}
+}
+
+// NewStepClients creates a client for each kind of step with newClient, and returns the
+// clients of the steps that use an LLM
+func NewStepClients(newClient func(kind string) (llm.LlmClient, error)) (StepClients, error) {
+	clients := make(StepClients)
+	for _, kind := range []string{ModelPlanning, ModelCode, ModelDocs} {
+		client, err := newClient(kind)
+		if err != nil {
+			return nil, err
+		}
+		for step := StepType(0); step < numSteps; step++ {
+			if ModelKind(step) == kind {
+				clients[step] = client
+			}
+		}
+	}
+	return clients, nil
+}
+
+// Model returns the model used for a kind of step
+func (r *Request) Model(kind string) string {
+	var model string
+	switch kind {
+	case ModelPlanning:
+		model = r.Models.Planning
+	case ModelCode:
+		model = r.Models.Code
+	case ModelDocs:
+		model = r.Models.Docs
+	}
+	if model == "" {
+		return r.ModelName
+	}
+	return model
+}
@@ -2,18 +2,29 @@ package core
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/santiagomed/boil/conventions"
 	"github.com/santiagomed/boil/fs"
 	"github.com/santiagomed/boil/llm"
+	"github.com/santiagomed/boil/verify"
 )
 
 type GenerateProjectDetailsStep struct {
-	llm llm.LlmClient
+	llm     llm.LlmClient
+	augment *AugmentOptions
 }
 
 func (s *GenerateProjectDetailsStep) Execute(state *State) error {
 	state.Logger.Info("Generating project details.")
-	details, err := llm.GenerateProjectDetails(s.llm, state.Request.ProjectDescription)
+	var details string
+	var err error
+	if s.augment != nil {
+		details, err = llm.GenerateAdditionDetails(s.llm, projectDescription(state.Request), s.augment.Summary)
+	} else {
+		details, err = llm.GenerateProjectDetails(s.llm, projectDescription(state.Request))
+	}
 	if err != nil {
 		state.Logger.Error(fmt.Sprintf("Failed to generate project details: %v", err))
 		return fmt.Errorf("failed to generate project details: %w", err)
@@ -23,13 +34,33 @@ func (s *GenerateProjectDetailsStep) Execute(state *State) error {
 	return nil
 }
 
+// projectDescription returns the description of a request with its extra instructions
+func projectDescription(r *Request) string {
+	if r.Instructions == "" {
+		return r.ProjectDescription
+	}
+	return fmt.Sprintf("%s\n\nAdditional instructions:\n%s", r.ProjectDescription, r.Instructions)
+}
+
 type GenerateFileTreeStep struct {
-	llm llm.LlmClient
+	llm     llm.LlmClient
+	augment *AugmentOptions
 }
 
 func (s *GenerateFileTreeStep) Execute(state *State) error {
 	state.Logger.Info("Generating file tree.")
-	fileTree, err := llm.GenerateFileTree(s.llm, state.ProjectDetails)
+	var fileTree string
+	var err error
+	if s.augment == nil && state.Request.FileTree != "" {
+		state.FileTree = state.Request.FileTree
+		state.Logger.Info("Using pinned file tree")
+		return nil
+	}
+	if s.augment != nil {
+		fileTree, err = llm.GenerateAdditionFileTree(s.llm, state.ProjectDetails, s.augment.Summary)
+	} else {
+		fileTree, err = llm.GenerateFileTree(s.llm, state.ProjectDetails)
+	}
 	if err != nil {
 		state.Logger.Error(fmt.Sprintf("Failed to generate file tree: %v", err))
 		return fmt.Errorf("failed to generate file tree: %w", err)
@@ -56,11 +87,21 @@ func (s *GenerateFileOperationsStep) Execute(state *State) error {
 }
 
 type ExecuteFileOperationsStep struct {
-	fs *fs.FileSystem
+	fs      *fs.FileSystem
+	augment *AugmentOptions
 }
 
 func (s *ExecuteFileOperationsStep) Execute(state *State) error {
 	state.Logger.Info("Executing file operations.")
+	operations := make([]fs.FileOperation, 0, len(state.FileOperations))
+	for _, op := range state.FileOperations {
+		if op.Operation == "CREATE_FILE" && s.augment.protects(op.Path) {
+			state.Logger.Warn(fmt.Sprintf("Skipping existing file %s", op.Path))
+			continue
+		}
+		operations = append(operations, op)
+	}
+	state.FileOperations = operations
 	err := s.fs.ExecuteFileOperations(state.FileOperations)
 	if err != nil {
 		state.Logger.Error(fmt.Sprintf("Failed to execute file operations: %v", err))
@@ -87,22 +128,33 @@ func (s *DetermineFileOrderStep) Execute(state *State) error {
 }
 
 type GenerateFileContentsStep struct {
-	llm llm.LlmClient
-	fs  *fs.FileSystem
+	llm        llm.LlmClient
+	fs         *fs.FileSystem
+	augment    *AugmentOptions
+	validators *verify.Validators
 }
 
 func (s *GenerateFileContentsStep) Execute(state *State) error {
 	state.Logger.Info("Generating file contents.")
+	conventions := stackConventions(state.Request.Conventions, s.fs)
 	for _, file := range state.FileOrder {
 		if s.fs.IsDir(file) {
 			continue
 		}
+		if s.augment.protects(file) {
+			state.Logger.Warn(fmt.Sprintf("Skipping existing file %s", file))
+			continue
+		}
 		state.Logger.Info(fmt.Sprintf("Generating content for file %s.", file))
-		content, err := llm.GenerateFileContent(s.llm, file, state.ProjectDetails, state.FileTree, state.PreviousFiles)
+		content, err := llm.GenerateFileContent(s.llm, file, state.ProjectDetails, state.FileTree, state.PreviousFiles, conventions)
 		if err != nil {
 			state.Logger.Error(fmt.Sprintf("Failed to generate content for file %s: %v", file, err))
 			return fmt.Errorf("failed to generate content for file %s: %w", file, err)
 		}
+		content, err = s.validate(state, file, content, conventions)
+		if err != nil {
+			return err
+		}
 		err = s.fs.WriteFile(file, content)
 		if err != nil {
 			state.Logger.Error(fmt.Sprintf("Failed to create file %s: %v", file, err))
@@ -115,11 +167,224 @@ func (s *GenerateFileContentsStep) Execute(state *State) error {
 	return nil
 }
 
-type CreateOptionalComponentsStep struct {
+// validate strips stray markdown fences and preambles from files with a validator, and asks the LLM once
+// to fix content that fails validation. Content that is still invalid is kept, and the
+// problem is recorded in the run report.
+func (s *GenerateFileContentsStep) validate(state *State, file, content, conventions string) (string, error) {
+	validator, ok := s.validators.For(file)
+	if !ok {
+		return content, nil
+	}
+	content = llm.CleanResponse(content)
+	validationErr := validator(content)
+	if validationErr == nil {
+		return content, nil
+	}
+
+	state.Logger.Warn(fmt.Sprintf("Generated content for file %s is invalid, regenerating: %v", file, validationErr))
+	fixed, err := llm.FixFileContent(s.llm, file, state.ProjectDetails, state.FileTree, state.PreviousFiles, conventions, content, validationErr.Error())
+	if err != nil {
+		state.Logger.Error(fmt.Sprintf("Failed to regenerate content for file %s: %v", file, err))
+		return "", fmt.Errorf("failed to regenerate content for file %s: %w", file, err)
+	}
+	fixed = llm.CleanResponse(fixed)
+	if validationErr = validator(fixed); validationErr != nil {
+		state.Logger.Warn(fmt.Sprintf("Regenerated content for file %s is still invalid: %v", file, validationErr))
+		state.Report.Issues = append(state.Report.Issues, verify.Issue{File: file, Message: validationErr.Error()})
+	}
+	return fixed, nil
+}
+
+type VerifyProjectStep struct {
+	fs *fs.FileSystem
+}
+
+// Execute builds the generated project in a temporary directory. A failed build is
+// recorded in the run report and published as an event, but does not fail the pipeline.
+func (s *VerifyProjectStep) Execute(state *State) error {
+	state.Logger.Info("Verifying project.")
+	result := runVerification(state, s.fs)
+	if err := state.Context().Err(); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	state.Report.Verification = result
+	publishVerification(state, VerifyProject, result)
+	return nil
+}
+
+type CheckProjectStep struct {
+	fs *fs.FileSystem
+}
+
+// Execute runs static checks on the generated files, which need no toolchain. Issues are
+// recorded in the run report and published as warnings, but do not fail the pipeline.
+func (s *CheckProjectStep) Execute(state *State) error {
+	state.Logger.Info("Checking project.")
+	issues, err := verify.CheckGo(s.fs)
+	if err != nil {
+		state.Logger.Error(fmt.Sprintf("Failed to check project: %v", err))
+		return fmt.Errorf("failed to check project: %w", err)
+	}
+	state.Report.Issues = append(state.Report.Issues, issues...)
+	if len(issues) == 0 {
+		state.Logger.Info("Project checked successfully")
+		return nil
+	}
+
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = "  " + issue.String()
+	}
+	message := fmt.Sprintf("Static checks found %d issue(s):\n%s", len(issues), strings.Join(lines, "\n"))
+	state.Logger.Warn(message)
+	state.PublishEvent(Event{Step: CheckProject, Type: EventCheck, Message: message})
+	return nil
+}
+
+type RepairProjectStep struct {
 	llm llm.LlmClient
 	fs  *fs.FileSystem
 }
 
+// Execute builds the generated project and regenerates the files the build reports errors
+// for, with the errors as context, until the build passes or Request.RepairRounds rounds
+// have run. Like verification, a project that still fails to build does not fail the pipeline.
+func (s *RepairProjectStep) Execute(state *State) error {
+	state.Logger.Info("Repairing project.")
+	result := runVerification(state, s.fs)
+	if err := state.Context().Err(); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+
+	for round := 1; !result.Passed && round <= state.Request.RepairRounds; round++ {
+		affected := verify.AffectedFiles(result.Output, generatedFiles(state))
+		if len(affected) == 0 {
+			state.Logger.Warn("Stopping repair: the build errors do not mention any generated file")
+			break
+		}
+
+		state.Logger.Info(fmt.Sprintf("Repair round %d: regenerating %d file(s).", round, len(affected)))
+		repaired, err := s.repairFiles(state, result.Command, affected)
+		if err != nil {
+			return err
+		}
+		state.Report.Repairs = append(state.Report.Repairs, RepairRound{Round: round, Build: result, Files: repaired})
+		state.PublishEvent(Event{
+			Step:    RepairProject,
+			Type:    EventRepair,
+			Message: fmt.Sprintf("Repair round %d: regenerated %s", round, strings.Join(repaired, ", ")),
+		})
+
+		result = runVerification(state, s.fs)
+		if err := state.Context().Err(); err != nil {
+			return err
+		}
+	}
+
+	state.Report.Verification = result
+	publishVerification(state, RepairProject, result)
+	return nil
+}
+
+// repairFiles regenerates the affected files with their build errors, returning the
+// repaired files in order
+func (s *RepairProjectStep) repairFiles(state *State, command string, affected map[string][]string) ([]string, error) {
+	conventions := stackConventions(state.Request.Conventions, s.fs)
+	files := make([]string, 0, len(affected))
+	for file := range affected {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		current := state.PreviousFiles[file]
+		others := make(map[string]string, len(state.PreviousFiles))
+		for name, content := range state.PreviousFiles {
+			if name != file {
+				others[name] = content
+			}
+		}
+		content, err := llm.RepairFileContent(s.llm, file, state.ProjectDetails, state.FileTree, others, conventions, current, command, affected[file])
+		if err != nil {
+			state.Logger.Error(fmt.Sprintf("Failed to repair file %s: %v", file, err))
+			return nil, fmt.Errorf("failed to repair file %s: %w", file, err)
+		}
+		if err := s.fs.WriteFile(file, content); err != nil {
+			state.Logger.Error(fmt.Sprintf("Failed to write file %s: %v", file, err))
+			return nil, fmt.Errorf("failed to write file %s: %w", file, err)
+		}
+		state.PreviousFiles[file] = content
+		state.Logger.Info(fmt.Sprintf("Repaired file %s", file))
+	}
+	return files, nil
+}
+
+// stackConventions returns the conventions for the stack detected in a project, if any
+func stackConventions(c *conventions.Conventions, project *fs.FileSystem) string {
+	stack, ok := verify.DetectStack(project)
+	if !ok {
+		return ""
+	}
+	return c.For(stack)
+}
+
+// generatedFiles returns the files whose contents were generated, in any order
+func generatedFiles(state *State) []string {
+	files := make([]string, 0, len(state.PreviousFiles))
+	for file := range state.PreviousFiles {
+		files = append(files, file)
+	}
+	return files
+}
+
+// runVerification builds the project with Request.VerifyCommand, or with the command
+// inferred from its manifest files. It returns nil if no command could be inferred. The
+// build is stopped when the pipeline is cancelled.
+func runVerification(state *State, fs *fs.FileSystem) *verify.Result {
+	command := state.Request.VerifyCommand
+	stack, detected := verify.DetectStack(fs)
+	if command == "" {
+		if !detected {
+			state.Logger.Warn("Skipping verification: no known project manifest found")
+			return nil
+		}
+		command = verify.DefaultCommand(stack)
+	}
+
+	result, err := verify.Run(state.Context(), fs, command, state.Request.VerifyTimeout)
+	if err != nil {
+		state.Logger.Error(fmt.Sprintf("Failed to verify project: %v", err))
+		result = &verify.Result{Command: command, ExitCode: -1, Output: err.Error()}
+	}
+	result.Stack = stack
+	return result
+}
+
+// publishVerification logs the result of a verification and publishes it as an event
+func publishVerification(state *State, step StepType, result *verify.Result) {
+	event := Event{Step: step, Type: EventVerification, Success: result.Passed}
+	if result.Passed {
+		state.Logger.Info(fmt.Sprintf("Project verified successfully with %q", result.Command))
+		event.Message = fmt.Sprintf("Verification passed: %s", result.Command)
+	} else {
+		state.Logger.Warn(fmt.Sprintf("Project verification with %q failed:\n%s", result.Command, result.Output))
+		event.Message = fmt.Sprintf("Verification failed: %s (exit code %d)", result.Command, result.ExitCode)
+	}
+	state.PublishEvent(event)
+}
+
+type CreateOptionalComponentsStep struct {
+	llm     llm.LlmClient
+	fs      *fs.FileSystem
+	augment *AugmentOptions
+}
+
 func (s *CreateOptionalComponentsStep) Execute(state *State) error {
 	state.Logger.Info("Creating optional components.")
 
@@ -132,7 +397,7 @@ func (s *CreateOptionalComponentsStep) Execute(state *State) error {
 		state.Logger.Info("Git repository initialized successfully")
 	}
 
-	if state.Request.GitIgnore {
+	if state.Request.GitIgnore && !s.augment.protects(".gitignore") {
 		state.Logger.Info("Creating .gitignore file.")
 		gitignore, err := llm.GenerateGitignoreContent(s.llm, state.ProjectDetails)
 		if err != nil {
@@ -146,7 +411,7 @@ func (s *CreateOptionalComponentsStep) Execute(state *State) error {
 		state.Logger.Info(".gitignore file created successfully")
 	}
 
-	if state.Request.Readme {
+	if state.Request.Readme && !s.augment.protects("README.md") {
 		state.Logger.Info("Generating README.md.")
 		readme, err := llm.GenerateReadmeContent(s.llm, state.ProjectDetails)
 		if err != nil {
@@ -160,7 +425,7 @@ func (s *CreateOptionalComponentsStep) Execute(state *State) error {
 		state.Logger.Info("README.md created successfully")
 	}
 
-	if state.Request.Dockerfile {
+	if state.Request.Dockerfile && !s.augment.protects("Dockerfile") {
 		state.Logger.Info("Generating Dockerfile.")
 		dockerfile, err := llm.GenerateDockerfileContent(s.llm, state.ProjectDetails)
 		if err != nil {
@@ -178,9 +443,27 @@ func (s *CreateOptionalComponentsStep) Execute(state *State) error {
 	return nil
 }
 
-type DoneStep struct{}
+type DoneStep struct {
+	fs *fs.FileSystem
+}
 
 func (s *DoneStep) Execute(state *State) error {
+	if usage := state.Request.Usage.Usage(); len(usage) > 0 && state.Report != nil {
+		state.Report.Usage = usage
+	}
+	if s.fs != nil && state.Request.SaveMetadata {
+		if err := SaveMetadata(s.fs, state); err != nil {
+			state.Logger.Error(fmt.Sprintf("Failed to save project metadata: %v", err))
+			return fmt.Errorf("failed to save project metadata: %w", err)
+		}
+		if !state.Report.empty() {
+			state.Report.Redact(state.Request.Secrets()...)
+			if err := SaveReport(s.fs, state.Report); err != nil {
+				state.Logger.Error(fmt.Sprintf("Failed to save run report: %v", err))
+				return fmt.Errorf("failed to save run report: %w", err)
+			}
+		}
+	}
 	state.Logger.Info("Project finalized successfully")
 	return nil
 }
@@ -196,16 +479,36 @@ type DefaultStepManager struct {
 }
 
 func NewDefaultStepManager(llm llm.LlmClient, fs *fs.FileSystem) *DefaultStepManager {
+	return NewStepManagerWithClients(nil, llm, fs)
+}
+
+// StepClients are the LLM clients of individual steps
+type StepClients map[StepType]llm.LlmClient
+
+// client returns the client of a step, or fallback if the step has none
+func (c StepClients) client(step StepType, fallback llm.LlmClient) llm.LlmClient {
+	if client, ok := c[step]; ok && client != nil {
+		return client
+	}
+	return fallback
+}
+
+// NewStepManagerWithClients creates a step manager whose steps use the client given for them
+// in clients, or else the fallback client. It lets steps run on different models.
+func NewStepManagerWithClients(clients StepClients, fallback llm.LlmClient, fs *fs.FileSystem) *DefaultStepManager {
 	return &DefaultStepManager{
 		stepMap: map[StepType]Step{
-			GenerateProjectDetails:   &GenerateProjectDetailsStep{llm: llm},
-			GenerateFileTree:         &GenerateFileTreeStep{llm: llm},
-			GenerateFileOperations:   &GenerateFileOperationsStep{llm: llm},
+			GenerateProjectDetails:   &GenerateProjectDetailsStep{llm: clients.client(GenerateProjectDetails, fallback)},
+			GenerateFileTree:         &GenerateFileTreeStep{llm: clients.client(GenerateFileTree, fallback)},
+			GenerateFileOperations:   &GenerateFileOperationsStep{llm: clients.client(GenerateFileOperations, fallback)},
 			ExecuteFileOperations:    &ExecuteFileOperationsStep{fs: fs},
-			DetermineFileOrder:       &DetermineFileOrderStep{llm: llm},
-			GenerateFileContents:     &GenerateFileContentsStep{llm: llm, fs: fs},
-			CreateOptionalComponents: &CreateOptionalComponentsStep{llm: llm, fs: fs},
-			Done:                     &DoneStep{},
+			DetermineFileOrder:       &DetermineFileOrderStep{llm: clients.client(DetermineFileOrder, fallback)},
+			GenerateFileContents:     &GenerateFileContentsStep{llm: clients.client(GenerateFileContents, fallback), fs: fs, validators: verify.NewValidators()},
+			CreateOptionalComponents: &CreateOptionalComponentsStep{llm: clients.client(CreateOptionalComponents, fallback), fs: fs},
+			Done:                     &DoneStep{fs: fs},
+			VerifyProject:            &VerifyProjectStep{fs: fs},
+			RepairProject:            &RepairProjectStep{llm: clients.client(RepairProject, fallback), fs: fs},
+			CheckProject:             &CheckProjectStep{fs: fs},
 		},
 		steps: []StepType{
 			GenerateProjectDetails,
@@ -227,3 +530,32 @@ func (sm *DefaultStepManager) GetStep(stepType StepType) Step {
 func (sm *DefaultStepManager) GetSteps() []StepType {
 	return sm.steps
 }
+
+// OptionalSteps returns the optional steps a request enables, in the order they run right
+// after GenerateFileContents
+func OptionalSteps(r *Request) []StepType {
+	var steps []StepType
+	if r.StaticChecks {
+		steps = append(steps, CheckProject)
+	}
+	if r.Repair {
+		steps = append(steps, RepairProject)
+	} else if r.Verify {
+		steps = append(steps, VerifyProject)
+	}
+	return steps
+}
+
+// InsertStepAfter runs stepType right after the step after, which must already be run by
+// the manager. Optional steps such as VerifyProject are enabled this way.
+func (sm *DefaultStepManager) InsertStepAfter(after, stepType StepType) {
+	for i, st := range sm.steps {
+		if st == after {
+			steps := make([]StepType, 0, len(sm.steps)+1)
+			steps = append(steps, sm.steps[:i+1]...)
+			steps = append(steps, stepType)
+			sm.steps = append(steps, sm.steps[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/spf13/afero"
+	"github.com/zalando/go-keyring"
+)
+
+// Service is the name credentials are stored under in the OS keyring
+const Service = "boil"
+
+// BackendEnv selects the credential store backend: keyring or file. By default the keyring
+// is used if it is available.
+const BackendEnv = "BOIL_CREDENTIAL_STORE"
+
+// PassphraseEnv holds the passphrase of the encrypted credentials file, to use it without a
+// prompt
+const PassphraseEnv = "BOIL_PASSPHRASE"
+
+// Backends of the credential store
+const (
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+)
+
+// ErrNotFound is returned when no secret is stored under a name
+var ErrNotFound = errors.New("credential not found")
+
+// Store stores secrets, such as API keys, by name
+type Store interface {
+	// Get returns the secret stored under name, or ErrNotFound
+	Get(name string) (string, error)
+	Set(name, secret string) error
+	// Delete removes the secret stored under name, or returns ErrNotFound
+	Delete(name string) error
+	// String describes where the secrets are stored
+	String() string
+}
+
+// PassphraseFunc returns the passphrase of an encrypted credentials file. create is set when
+// the file doesn't exist yet, so the passphrase can be confirmed.
+type PassphraseFunc func(create bool) (string, error)
+
+// DefaultFilePath returns the path of the encrypted credentials file
+func DefaultFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user home directory: %w", err)
+	}
+	return filepath.Join(home, ".boil", "credentials.age"), nil
+}
+
+// Open returns the credential store selected by BackendEnv: the OS keyring (the Secret
+// Service on Linux, the Keychain on macOS and the Credential Manager on Windows) if it is
+// available, or else the encrypted credentials file
+func Open(fs afero.Fs, passphrase PassphraseFunc) (Store, error) {
+	backend := os.Getenv(BackendEnv)
+	switch backend {
+	case BackendKeyring:
+		return NewKeyringStore(), nil
+	case BackendFile, "":
+		if backend == "" && KeyringAvailable() {
+			return NewKeyringStore(), nil
+		}
+		path, err := DefaultFilePath()
+		if err != nil {
+			return nil, err
+		}
+		return NewFileStore(fs, path, passphrase), nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q in %s (supported: %s, %s)", backend, BackendEnv, BackendKeyring, BackendFile)
+	}
+}
+
+// KeyringStore stores secrets in the OS keyring
+type KeyringStore struct {
+	service string
+}
+
+// NewKeyringStore creates a store of the OS keyring
+func NewKeyringStore() *KeyringStore {
+	return &KeyringStore{service: Service}
+}
+
+// KeyringAvailable reports whether the OS keyring can be used
+func KeyringAvailable() bool {
+	_, err := keyring.Get(Service, "availability-check")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+func (s *KeyringStore) Get(name string) (string, error) {
+	secret, err := keyring.Get(s.service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %s from the keyring: %w", name, err)
+	}
+	return secret, nil
+}
+
+func (s *KeyringStore) Set(name, secret string) error {
+	if err := keyring.Set(s.service, name, secret); err != nil {
+		return fmt.Errorf("error writing %s to the keyring: %w", name, err)
+	}
+	return nil
+}
+
+func (s *KeyringStore) Delete(name string) error {
+	err := keyring.Delete(s.service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting %s from the keyring: %w", name, err)
+	}
+	return nil
+}
+
+func (s *KeyringStore) String() string {
+	return "OS keyring"
+}
+
+// FileStore stores secrets in a file encrypted with a passphrase, in the age format. The
+// passphrase is asked for once, the first time the file is read or written.
+type FileStore struct {
+	fs         afero.Fs
+	path       string
+	passphrase PassphraseFunc
+	// workFactor is the scrypt work factor of new files, or 0 for age's default
+	workFactor int
+
+	mu      sync.Mutex
+	pass    string
+	secrets map[string]string
+}
+
+// NewFileStore creates a store of an encrypted credentials file
+func NewFileStore(fs afero.Fs, path string, passphrase PassphraseFunc) *FileStore {
+	return &FileStore{fs: fs, path: path, passphrase: passphrase}
+}
+
+func (s *FileStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists, err := afero.Exists(s.fs, s.path)
+	if err != nil {
+		return "", fmt.Errorf("error reading credentials file: %w", err)
+	}
+	if !exists {
+		// Don't ask for a passphrase when nothing is stored
+		return "", ErrNotFound
+	}
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	secret, ok := s.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s *FileStore) Set(name, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.secrets[name] = secret
+	return s.save()
+}
+
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists, err := afero.Exists(s.fs, s.path)
+	if err != nil {
+		return fmt.Errorf("error reading credentials file: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.secrets[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.secrets, name)
+	return s.save()
+}
+
+func (s *FileStore) String() string {
+	return "encrypted file " + s.path
+}
+
+// load decrypts the credentials file, asking for the passphrase if needed
+func (s *FileStore) load() error {
+	if s.secrets != nil {
+		return nil
+	}
+
+	content, err := afero.ReadFile(s.fs, s.path)
+	if os.IsNotExist(err) {
+		if s.pass, err = s.askPassphrase(true); err != nil {
+			return err
+		}
+		s.secrets = make(map[string]string)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading credentials file: %w", err)
+	}
+
+	pass, err := s.askPassphrase(false)
+	if err != nil {
+		return err
+	}
+	identity, err := age.NewScryptIdentity(pass)
+	if err != nil {
+		return fmt.Errorf("error decrypting credentials file: %w", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(content), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return fmt.Errorf("error decrypting credentials file %s: incorrect passphrase", s.path)
+		}
+		return fmt.Errorf("error decrypting credentials file %s: %w", s.path, err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("error decrypting credentials file %s: %w", s.path, err)
+	}
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return fmt.Errorf("error parsing credentials file %s: %w", s.path, err)
+	}
+	s.pass = pass
+	s.secrets = secrets
+	return nil
+}
+
+// save encrypts the secrets to the credentials file
+func (s *FileStore) save() error {
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return fmt.Errorf("error encoding credentials: %w", err)
+	}
+	recipient, err := age.NewScryptRecipient(s.pass)
+	if err != nil {
+		return fmt.Errorf("error encrypting credentials: %w", err)
+	}
+	if s.workFactor > 0 {
+		recipient.SetWorkFactor(s.workFactor)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return fmt.Errorf("error encrypting credentials: %w", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		return fmt.Errorf("error encrypting credentials: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error encrypting credentials: %w", err)
+	}
+
+	if err := s.fs.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("error creating credentials directory: %w", err)
+	}
+	if err := afero.WriteFile(s.fs, s.path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing credentials file: %w", err)
+	}
+	return nil
+}
+
+// askPassphrase returns the passphrase of the credentials file from PassphraseEnv or the
+// PassphraseFunc
+func (s *FileStore) askPassphrase(create bool) (string, error) {
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		return pass, nil
+	}
+	if s.passphrase == nil {
+		return "", fmt.Errorf("a passphrase is required for the credentials file %s (set %s)", s.path, PassphraseEnv)
+	}
+	pass, err := s.passphrase(create)
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", errors.New("the passphrase of the credentials file can't be empty")
+	}
+	return pass, nil
+}
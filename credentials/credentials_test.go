@@ -0,0 +1,72 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	memFs := afero.NewMemMapFs()
+	asked := 0
+	passphrase := func(create bool) (string, error) {
+		asked++
+		return "correct horse", nil
+	}
+	store := NewFileStore(memFs, "/home/.boil/credentials.age", passphrase)
+	store.workFactor = 10
+
+	_, err := store.Get("openai")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 0, asked, "no passphrase is needed when nothing is stored")
+	assert.ErrorIs(t, store.Delete("openai"), ErrNotFound)
+
+	require.NoError(t, store.Set("openai", "sk-secret"))
+	require.NoError(t, store.Set("anthropic", "sk-ant-secret"))
+	assert.Equal(t, 1, asked)
+
+	content, err := afero.ReadFile(memFs, "/home/.boil/credentials.age")
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "sk-secret")
+	info, err := memFs.Stat("/home/.boil/credentials.age")
+	require.NoError(t, err)
+	assert.Equal(t, "-rw-------", info.Mode().String())
+
+	reopened := NewFileStore(memFs, "/home/.boil/credentials.age", passphrase)
+	reopened.workFactor = 10
+	secret, err := reopened.Get("openai")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", secret)
+	require.NoError(t, reopened.Delete("openai"))
+	_, err = reopened.Get("openai")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	wrong := NewFileStore(memFs, "/home/.boil/credentials.age", func(bool) (string, error) { return "wrong", nil })
+	_, err = wrong.Get("anthropic")
+	assert.ErrorContains(t, err, "incorrect passphrase")
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	fromEnv := NewFileStore(memFs, "/home/.boil/credentials.age", nil)
+	secret, err = fromEnv.Get("anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-ant-secret", secret)
+}
+
+func TestKeyringStore(t *testing.T) {
+	keyring.MockInit()
+	store := NewKeyringStore()
+
+	_, err := store.Get("openai")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	require.NoError(t, store.Set("openai", "sk-secret"))
+	secret, err := store.Get("openai")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", secret)
+	require.NoError(t, store.Delete("openai"))
+	assert.ErrorIs(t, store.Delete("openai"), ErrNotFound)
+}
@@ -0,0 +1,224 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ArchiveFormat is a supported project archive format
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// archiveTime is the modification time of every archive entry, so that identical
+// projects produce byte-identical archives. It is the earliest time a zip can represent.
+var archiveTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ParseArchiveFormat parses an archive format name
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), ".") {
+	case "zip":
+		return ArchiveZip, nil
+	case "tar.gz", "tgz":
+		return ArchiveTarGz, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q: must be zip or tar.gz", s)
+	}
+}
+
+// ArchiveFormatFromPath determines the archive format from a file name extension
+func ArchiveFormatFromPath(path string) (ArchiveFormat, error) {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, nil
+	default:
+		return "", fmt.Errorf("cannot determine archive format of %s: use a .zip, .tar.gz or .tgz extension", path)
+	}
+}
+
+// archiveEntry is a file or directory of the file system to be archived
+type archiveEntry struct {
+	path string
+	name string
+	info os.FileInfo
+}
+
+// walkArchiveEntries calls fn for every entry of the file system in lexical order,
+// with slash-separated names relative to the root
+func (fs *FileSystem) walkArchiveEntries(fn func(entry archiveEntry) error) error {
+	fileCount := 0
+	err := afero.Walk(fs.Fs, ".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip root directory
+		if path == "." {
+			return nil
+		}
+
+		if !info.IsDir() {
+			fileCount++
+		}
+		return fn(archiveEntry{path: path, name: filepath.ToSlash(path), info: info})
+	})
+	if err != nil {
+		return fmt.Errorf("error walking file system: %w", err)
+	}
+	if fileCount == 0 {
+		return fmt.Errorf("no files to archive")
+	}
+	return nil
+}
+
+// copyEntry copies the content of a file of the file system to w
+func (fs *FileSystem) copyEntry(w io.Writer, path string) error {
+	file, err := fs.Fs.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("error writing file %s to archive: %w", path, err)
+	}
+	return nil
+}
+
+// WriteZip writes the file system to w as a zip archive. Entries are written in lexical
+// order with fixed timestamps, so identical projects produce identical archives.
+func (fs *FileSystem) WriteZip(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := fs.walkArchiveEntries(func(entry archiveEntry) error {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: archiveTime}
+		header.SetMode(permOrDefault(entry.info))
+
+		if entry.info.IsDir() {
+			header.Name += "/"
+			header.Method = zip.Store
+			header.SetMode(os.ModeDir | permOrDefault(entry.info))
+			if _, err := zipWriter.CreateHeader(header); err != nil {
+				return fmt.Errorf("error creating zip entry for directory %s: %w", entry.name, err)
+			}
+			return nil
+		}
+
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("error creating zip entry for file %s: %w", entry.name, err)
+		}
+		return fs.copyEntry(writer, entry.path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("error closing zip writer: %w", err)
+	}
+	return nil
+}
+
+// WriteTarGz writes the file system to w as a gzip-compressed tar archive. Entries are
+// written in lexical order with fixed timestamps and owners, so identical projects
+// produce identical archives.
+func (fs *FileSystem) WriteTarGz(w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err := fs.walkArchiveEntries(func(entry archiveEntry) error {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    int64(permOrDefault(entry.info)),
+			ModTime: archiveTime,
+			Format:  tar.FormatPAX,
+		}
+
+		if entry.info.IsDir() {
+			header.Name += "/"
+			header.Typeflag = tar.TypeDir
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return fmt.Errorf("error creating tar entry for directory %s: %w", entry.name, err)
+			}
+			return nil
+		}
+
+		header.Typeflag = tar.TypeReg
+		header.Size = entry.info.Size()
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("error creating tar entry for file %s: %w", entry.name, err)
+		}
+		return fs.copyEntry(tarWriter, entry.path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("error closing tar writer: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("error closing gzip writer: %w", err)
+	}
+	return nil
+}
+
+// WriteArchive writes the file system to w in the given archive format
+func (fs *FileSystem) WriteArchive(w io.Writer, format ArchiveFormat) error {
+	switch format {
+	case ArchiveZip:
+		return fs.WriteZip(w)
+	case ArchiveTarGz:
+		return fs.WriteTarGz(w)
+	default:
+		return fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+// WriteArchiveFile writes the file system as an archive to path on dstFS. The archive is
+// written to a temporary file next to path and renamed into place once complete.
+func (fs *FileSystem) WriteArchiveFile(dstFS afero.Fs, path string, format ArchiveFormat) error {
+	dir := filepath.Dir(path)
+	if err := dstFS.MkdirAll(dir, DefaultDirMode); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	tmp, err := afero.TempFile(dstFS, dir, "."+filepath.Base(path)+".boil-")
+	if err != nil {
+		return fmt.Errorf("error creating temporary archive: %w", err)
+	}
+	tmpPath := tmp.Name()
+	// The temporary file is renamed on success, so this only cleans up after failures
+	defer dstFS.Remove(tmpPath)
+
+	if err := fs.WriteArchive(tmp, format); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %w", err)
+	}
+	if err := dstFS.Chmod(tmpPath, DefaultFileMode); err != nil {
+		return fmt.Errorf("error setting archive mode: %w", err)
+	}
+	if err := dstFS.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error moving archive into place: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+// ChangeType describes how a file differs from the file it would replace
+type ChangeType string
+
+const (
+	// ChangeAdded is a file that does not exist in the destination yet
+	ChangeAdded ChangeType = "added"
+	// ChangeModified is a file whose content or mode differs from the destination
+	ChangeModified ChangeType = "modified"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// FileDiff describes the changes writing a single file would make
+type FileDiff struct {
+	// Path is the slash-separated path of the file, relative to the compared directories
+	Path    string
+	Change  ChangeType
+	OldMode os.FileMode
+	NewMode os.FileMode
+	// Unified is the unified diff of the file contents, empty if only the mode changed
+	Unified string
+}
+
+// Diff compares the files under srcPath with the files they would replace under dstPath
+// on dstFS, and returns the files that would be added or modified, sorted by path.
+// Files that only exist under dstPath are not reported, as writing never removes them.
+func (fs *FileSystem) Diff(dstFS afero.Fs, srcPath, dstPath string) ([]FileDiff, error) {
+	var diffs []FileDiff
+	err := afero.Walk(fs.Fs, srcPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path: %w", err)
+		}
+		slashPath := filepath.ToSlash(relPath)
+
+		newContent, err := afero.ReadFile(fs.Fs, path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		fileDiff := FileDiff{Path: slashPath, NewMode: permOrDefault(info)}
+
+		dstItemPath := filepath.Join(dstPath, relPath)
+		dstInfo, err := dstFS.Stat(dstItemPath)
+		if os.IsNotExist(err) {
+			fileDiff.Change = ChangeAdded
+			fileDiff.Unified = UnifiedDiff(slashPath, nil, newContent, false)
+			diffs = append(diffs, fileDiff)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error accessing %s: %w", dstItemPath, err)
+		}
+		if dstInfo.IsDir() {
+			return fmt.Errorf("cannot replace directory %s with a file", dstItemPath)
+		}
+
+		oldContent, err := afero.ReadFile(dstFS, dstItemPath)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", dstItemPath, err)
+		}
+		fileDiff.OldMode = permOrDefault(dstInfo)
+		if bytes.Equal(oldContent, newContent) && fileDiff.OldMode == fileDiff.NewMode {
+			return nil
+		}
+		fileDiff.Change = ChangeModified
+		if !bytes.Equal(oldContent, newContent) {
+			fileDiff.Unified = UnifiedDiff(slashPath, oldContent, newContent, true)
+		}
+		diffs = append(diffs, fileDiff)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error comparing file systems: %w", err)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// UnifiedDiff returns a unified diff between the old and new content of a file. If the
+// file does not exist yet (exists is false) the diff is against /dev/null.
+func UnifiedDiff(path string, oldContent, newContent []byte, exists bool) string {
+	fromFile := "a/" + path
+	if !exists {
+		fromFile = "/dev/null"
+	}
+	if isBinary(oldContent) || isBinary(newContent) {
+		return fmt.Sprintf("Binary files %s and b/%s differ\n", fromFile, path)
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(oldContent),
+		B:        splitLines(newContent),
+		FromFile: fromFile,
+		ToFile:   "b/" + path,
+		Context:  diffContext,
+	})
+	if err != nil {
+		// Writing to a buffer cannot fail
+		return ""
+	}
+	return diff
+}
+
+// splitLines splits content into lines, keeping line endings and terminating the last line
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := difflib.SplitLines(string(content))
+	// SplitLines appends a newline to the last line, which is empty for terminated content
+	if last := lines[len(lines)-1]; last == "\n" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isBinary reports whether content looks like binary data
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// Subset returns a new in-memory file system holding only the given files of the file
+// system, identified by slash-separated paths, with their modes
+func (fs *FileSystem) Subset(paths []string) (*FileSystem, error) {
+	subset := NewMemoryFileSystem()
+	for _, path := range paths {
+		path = filepath.FromSlash(path)
+		info, err := fs.Fs.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error accessing %s: %w", path, err)
+		}
+		dir := filepath.Dir(path)
+		if err := subset.Fs.MkdirAll(dir, DefaultDirMode); err != nil {
+			return nil, fmt.Errorf("error creating directory %s: %w", dir, err)
+		}
+		if err := copyFile(fs.Fs, subset.Fs, path, path, permOrDefault(info)); err != nil {
+			return nil, err
+		}
+	}
+	return subset, nil
+}
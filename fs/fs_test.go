@@ -1,8 +1,17 @@
 package fs
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/santiagomed/boil/utils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
@@ -95,3 +104,574 @@ func TestListFiles(t *testing.T) {
 	assert.NotNil(t, structure)
 	assert.Equal(t, map[string]interface{}{"test": map[string]interface{}{"file.txt": nil}}, structure)
 }
+
+func TestInferFileMode(t *testing.T) {
+	tests := []struct {
+		path     string
+		content  string
+		expected os.FileMode
+	}{
+		{"main.go", "package main", DefaultFileMode},
+		{"scripts/run", "#!/bin/sh\necho hi", ExecFileMode},
+		{"scripts/setup.sh", "echo hi", ExecFileMode},
+		{"mvnw", "", ExecFileMode},
+		{"bin/server", "", ExecFileMode},
+		{"src/bin/main.rs", "fn main() {}", ExecFileMode},
+		{"binary/data.txt", "", DefaultFileMode},
+		{"README.md", "# #!not a shebang", DefaultFileMode},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, InferFileMode(tt.path, tt.content), tt.path)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	mode, err := ParseFileMode("0755")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), mode)
+
+	mode, err = ParseFileMode("600")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), mode)
+
+	_, err = ParseFileMode("rwx")
+	assert.Error(t, err)
+
+	_, err = ParseFileMode("4755")
+	assert.Error(t, err)
+}
+
+func TestWriteFileMode(t *testing.T) {
+	fs := NewMemoryFileSystem()
+
+	err := fs.WriteFile("run.sh", "#!/bin/sh\necho hi")
+	assert.NoError(t, err)
+	info, err := fs.Fs.Stat("run.sh")
+	assert.NoError(t, err)
+	assert.Equal(t, ExecFileMode, info.Mode().Perm())
+
+	err = fs.WriteFile("main.go", "package main")
+	assert.NoError(t, err)
+	info, err = fs.Fs.Stat("main.go")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFileMode, info.Mode().Perm())
+}
+
+func TestFileOperationModeOverride(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	operations := []FileOperation{
+		{Operation: "CREATE_FILE", Path: "tools/deploy", Mode: "0750"},
+		{Operation: "CREATE_FILE", Path: "bin/config.txt", Mode: "0644"},
+	}
+
+	err := fs.ExecuteFileOperations(operations)
+	assert.NoError(t, err)
+
+	// Content written later must not override the mode set by the operation
+	assert.NoError(t, fs.WriteFile("tools/deploy", "deploy"))
+	assert.NoError(t, fs.WriteFile("bin/config.txt", "#!not really"))
+
+	info, err := fs.Fs.Stat("tools/deploy")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+
+	info, err = fs.Fs.Stat("bin/config.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	err = fs.ExecuteFileOperation(FileOperation{Operation: "CREATE_FILE", Path: "bad", Mode: "abc"})
+	assert.Error(t, err)
+}
+
+func TestCopyDirPreservesMode(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("bin/start", "#!/bin/sh\nexec app"))
+	assert.NoError(t, fs.WriteFile("app.txt", "hello"))
+
+	dst := afero.NewMemMapFs()
+	err := fs.CopyDir(dst, ".", "out")
+	assert.NoError(t, err)
+
+	info, err := dst.Stat("out/bin/start")
+	assert.NoError(t, err)
+	assert.Equal(t, ExecFileMode, info.Mode().Perm())
+
+	info, err = dst.Stat("out/app.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFileMode, info.Mode().Perm())
+}
+
+func TestZipRoundTripPreservesMode(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("mvnw", "#!/bin/sh\nexec java"))
+	assert.NoError(t, fs.WriteFile("src/App.java", "class App {}"))
+
+	zipBytes, err := fs.WriteToZip()
+	assert.NoError(t, err)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	assert.NoError(t, err)
+	modes := make(map[string]os.FileMode)
+	for _, f := range zipReader.File {
+		modes[f.Name] = f.Mode().Perm()
+	}
+	assert.Equal(t, ExecFileMode, modes["mvnw"])
+	assert.Equal(t, DefaultFileMode, modes["src/App.java"])
+
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "project.zip")
+	assert.NoError(t, os.WriteFile(zipPath, zipBytes, 0644))
+
+	out := filepath.Join(dir, "out")
+	assert.NoError(t, utils.Unzip(zipPath, out))
+
+	info, err := os.Stat(filepath.Join(out, "mvnw"))
+	assert.NoError(t, err)
+	assert.Equal(t, ExecFileMode, info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Join(out, "src", "App.java"))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFileMode, info.Mode().Perm())
+}
+
+func newProjectFileSystem(t *testing.T) *FileSystem {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("main.go", "package main"))
+	assert.NoError(t, fs.WriteFile("go.mod", "module example"))
+	return fs
+}
+
+func TestParseExistsPolicy(t *testing.T) {
+	for _, p := range ExistsPolicies {
+		policy, err := ParseExistsPolicy(string(p))
+		assert.NoError(t, err)
+		assert.Equal(t, p, policy)
+	}
+
+	_, err := ParseExistsPolicy("replace")
+	assert.Error(t, err)
+}
+
+func TestWriteProjectNewDirectory(t *testing.T) {
+	fs := newProjectFileSystem(t)
+	dst := afero.NewMemMapFs()
+
+	report, err := fs.WriteProject(dst, ".", "out/project", ExistsFail)
+	assert.NoError(t, err)
+	assert.Equal(t, "out/project", report.Path)
+	assert.Empty(t, report.Conflicts)
+
+	content, err := afero.ReadFile(dst, "out/project/main.go")
+	assert.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+
+	// No staging directories are left behind
+	entries, err := afero.ReadDir(dst, "out")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteProjectExistingDirectory(t *testing.T) {
+	setup := func(t *testing.T) afero.Fs {
+		dst := afero.NewMemMapFs()
+		assert.NoError(t, afero.WriteFile(dst, "project/main.go", []byte("old"), 0644))
+		assert.NoError(t, afero.WriteFile(dst, "project/notes.txt", []byte("keep"), 0644))
+		return dst
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		dst := setup(t)
+		_, err := newProjectFileSystem(t).WriteProject(dst, ".", "project", ExistsFail)
+		var existsErr *OutputExistsError
+		assert.ErrorAs(t, err, &existsErr)
+
+		content, err := afero.ReadFile(dst, "project/main.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "old", string(content))
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		dst := setup(t)
+		report, err := newProjectFileSystem(t).WriteProject(dst, ".", "project", ExistsOverwrite)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"main.go"}, report.Conflicts)
+
+		content, err := afero.ReadFile(dst, "project/main.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+
+		// Files the project did not generate are kept
+		content, err = afero.ReadFile(dst, "project/notes.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "keep", string(content))
+		entries, err := afero.ReadDir(dst, ".")
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		dst := setup(t)
+		report, err := newProjectFileSystem(t).WriteProject(dst, ".", "project", ExistsMerge)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"main.go"}, report.Conflicts)
+
+		content, err := afero.ReadFile(dst, "project/main.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+
+		content, err = afero.ReadFile(dst, "project/notes.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "keep", string(content))
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		dst := setup(t)
+		report, err := newProjectFileSystem(t).WriteProject(dst, ".", "project", ExistsBackup)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, report.BackupPath)
+
+		content, err := afero.ReadFile(dst, "project/main.go")
+		assert.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+
+		content, err = afero.ReadFile(dst, filepath.Join(report.BackupPath, "main.go"))
+		assert.NoError(t, err)
+		assert.Equal(t, "old", string(content))
+	})
+
+	t.Run("backup names are unique", func(t *testing.T) {
+		dst := setup(t)
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		first, err := backupPath(dst, "project", now)
+		assert.NoError(t, err)
+		assert.Equal(t, "project.bak-20240501-120000", first)
+		assert.NoError(t, dst.MkdirAll(first, DefaultDirMode))
+
+		second, err := backupPath(dst, "project", now)
+		assert.NoError(t, err)
+		assert.Equal(t, "project.bak-20240501-120000-2", second)
+	})
+
+	t.Run("merge rolls back on failure", func(t *testing.T) {
+		dst := setup(t)
+		assert.NoError(t, afero.WriteFile(dst, "project/go.mod", []byte("old module"), 0644))
+		assert.NoError(t, dst.Remove("project/main.go"))
+		assert.NoError(t, dst.MkdirAll("project/main.go", DefaultDirMode))
+
+		_, err := newProjectFileSystem(t).WriteProject(dst, ".", "project", ExistsMerge)
+		assert.ErrorContains(t, err, "cannot replace directory")
+
+		content, err := afero.ReadFile(dst, "project/go.mod")
+		assert.NoError(t, err)
+		assert.Equal(t, "old module", string(content))
+		entries, err := afero.ReadDir(dst, ".")
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+}
+
+func TestWriteProjectMergeKeepsSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside")
+	assert.NoError(t, os.MkdirAll(filepath.Join(outside, "pkg"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "notes.txt"), []byte("notes"), 0644))
+	project := filepath.Join(dir, "project")
+	assert.NoError(t, os.MkdirAll(project, 0755))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "pkg"), filepath.Join(project, "node_modules")))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "notes.txt"), filepath.Join(project, "notes.txt")))
+
+	report, err := newProjectFileSystem(t).WriteProject(afero.NewOsFs(), ".", project, ExistsMerge)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Conflicts)
+
+	for _, name := range []string{"node_modules", "notes.txt"} {
+		info, err := os.Lstat(filepath.Join(project, name))
+		assert.NoError(t, err)
+		assert.Equal(t, os.ModeSymlink, info.Mode()&os.ModeSymlink, name)
+	}
+	content, err := os.ReadFile(filepath.Join(project, "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+}
+
+func TestEnsureWritableDir(t *testing.T) {
+	dst := afero.NewMemMapFs()
+	err := EnsureWritableDir(dst, "projects/nested")
+	assert.NoError(t, err)
+	assert.True(t, (&FileSystem{Fs: dst}).IsDir("projects/nested"))
+
+	// The write check leaves nothing behind
+	empty, err := afero.IsEmpty(dst, "projects/nested")
+	assert.NoError(t, err)
+	assert.True(t, empty)
+
+	assert.NoError(t, afero.WriteFile(dst, "file.txt", []byte("x"), 0644))
+	assert.Error(t, EnsureWritableDir(dst, "file.txt"))
+
+	assert.Error(t, EnsureWritableDir(afero.NewReadOnlyFs(afero.NewMemMapFs()), "projects"))
+}
+
+func TestArchiveFormatFromPath(t *testing.T) {
+	format, err := ArchiveFormatFromPath("out/project.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, ArchiveZip, format)
+
+	format, err = ArchiveFormatFromPath("project.TAR.GZ")
+	assert.NoError(t, err)
+	assert.Equal(t, ArchiveTarGz, format)
+
+	format, err = ArchiveFormatFromPath("project.tgz")
+	assert.NoError(t, err)
+	assert.Equal(t, ArchiveTarGz, format)
+
+	_, err = ArchiveFormatFromPath("project.rar")
+	assert.Error(t, err)
+}
+
+func TestWriteArchiveDeterministic(t *testing.T) {
+	build := func() *FileSystem {
+		fs := NewMemoryFileSystem()
+		assert.NoError(t, fs.WriteFile("src/main.go", "package main"))
+		assert.NoError(t, fs.WriteFile("go.mod", "module example"))
+		assert.NoError(t, fs.WriteFile("bin/run", "#!/bin/sh"))
+		return fs
+	}
+
+	// Build the projects at different times so file modification times differ
+	first := build()
+	time.Sleep(1100 * time.Millisecond)
+	second := build()
+
+	for _, format := range []ArchiveFormat{ArchiveZip, ArchiveTarGz} {
+		var firstBuf, secondBuf bytes.Buffer
+		assert.NoError(t, first.WriteArchive(&firstBuf, format))
+		assert.NoError(t, second.WriteArchive(&secondBuf, format))
+		assert.Equal(t, firstBuf.Bytes(), secondBuf.Bytes(), string(format))
+	}
+}
+
+func TestWriteTarGz(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("src/main.go", "package main"))
+	assert.NoError(t, fs.WriteFile("gradlew", "#!/bin/sh"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, fs.WriteTarGz(&buf))
+
+	gzipReader, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	var names []string
+	contents := make(map[string]string)
+	modes := make(map[string]int64)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+		modes[header.Name] = header.Mode
+		content, err := io.ReadAll(tarReader)
+		assert.NoError(t, err)
+		contents[header.Name] = string(content)
+	}
+
+	assert.Equal(t, []string{"gradlew", "src/", "src/main.go"}, names)
+	assert.Equal(t, "package main", contents["src/main.go"])
+	assert.Equal(t, int64(ExecFileMode), modes["gradlew"])
+	assert.Equal(t, int64(DefaultFileMode), modes["src/main.go"])
+}
+
+func TestWriteArchiveFile(t *testing.T) {
+	fs := newProjectFileSystem(t)
+	dst := afero.NewMemMapFs()
+
+	err := fs.WriteArchiveFile(dst, "archives/project.zip", ArchiveZip)
+	assert.NoError(t, err)
+
+	entries, err := afero.ReadDir(dst, "archives")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "project.zip", entries[0].Name())
+
+	zipBytes, err := afero.ReadFile(dst, "archives/project.zip")
+	assert.NoError(t, err)
+	zipReader, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	assert.NoError(t, err)
+	assert.Len(t, zipReader.File, 2)
+
+	assert.Error(t, NewMemoryFileSystem().WriteArchiveFile(dst, "empty.zip", ArchiveZip))
+	exists, err := afero.Exists(dst, "empty.zip")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestGitIgnore(t *testing.T) {
+	ignore := NewGitIgnore()
+	ignore.AddPattern("# comment", "")
+	ignore.AddPattern("*.log", "")
+	ignore.AddPattern("!keep.log", "")
+	ignore.AddPattern("node_modules/", "")
+	ignore.AddPattern("/dist", "")
+	ignore.AddPattern("docs/**/*.tmp", "")
+	ignore.AddPattern("generated", "services/api")
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false},
+		{"dist", true, true},
+		{"web/dist", true, false},
+		{"docs/a/b/c.tmp", false, true},
+		{"docs/c.tmp", false, true},
+		{"services/api/generated", true, true},
+		{"services/api/v1/generated", false, true},
+		{"services/web/generated", false, false},
+		{"main.go", false, false},
+		{"# comment", false, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ignore.Match(tt.path, tt.isDir), tt.path)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	src := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(src, "repo/.gitignore", []byte("*.log\nbuild/\n"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/go.mod", []byte("module example.com/repo"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/cmd/main.go", []byte("package main"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/debug.log", []byte("log"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/build/out", []byte("bin"), 0755))
+	assert.NoError(t, afero.WriteFile(src, "repo/web/.gitignore", []byte("/cache\n"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/web/cache/data", []byte("x"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/web/index.js", []byte("x"), 0644))
+	assert.NoError(t, afero.WriteFile(src, "repo/.git/HEAD", []byte("ref"), 0644))
+
+	loaded, err := LoadDir(src, "repo")
+	assert.NoError(t, err)
+
+	structure, err := loaded.ListFiles(".")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		".gitignore": nil,
+		"go.mod":     nil,
+		"cmd":        map[string]interface{}{"main.go": nil},
+		"web":        map[string]interface{}{".gitignore": nil, "index.js": nil},
+	}, structure)
+
+	summary, err := loaded.Summarize(100)
+	assert.NoError(t, err)
+	assert.Contains(t, summary, "project-root/\n")
+	assert.Contains(t, summary, "└── main.go\n")
+	assert.Contains(t, summary, "// file: go.mod\nmodule example.com/repo")
+
+	_, err = LoadDir(src, "missing")
+	assert.Error(t, err)
+}
+
+func TestTree(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("src/main.go", "package main"))
+	assert.NoError(t, fs.WriteFile("src/util/util.go", "package util"))
+	assert.NoError(t, fs.WriteFile("go.mod", "module example"))
+
+	tree, err := fs.Tree(100)
+	assert.NoError(t, err)
+	assert.Equal(t, `project-root/
+├── go.mod
+└── src/
+    ├── main.go
+    └── util/
+        └── util.go
+`, tree)
+
+	tree, err = fs.Tree(2)
+	assert.NoError(t, err)
+	assert.Equal(t, `project-root/
+├── go.mod
+└── src/
+... (3 more entries)
+`, tree)
+}
+
+func TestDiff(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("main.go", "package main\n\nfunc main() {\n\tprintln(\"new\")\n}\n"))
+	assert.NoError(t, fs.WriteFile("go.mod", "module example\n"))
+	assert.NoError(t, fs.WriteFile("run.sh", "echo hi\n"))
+	assert.NoError(t, fs.WriteFile("docs/guide.md", "# Guide\n"))
+
+	dst := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(dst, "project/main.go", []byte("package main\n\nfunc main() {\n\tprintln(\"old\")\n}\n"), 0644))
+	assert.NoError(t, afero.WriteFile(dst, "project/go.mod", []byte("module example\n"), 0644))
+	assert.NoError(t, afero.WriteFile(dst, "project/run.sh", []byte("echo hi\n"), 0644))
+	assert.NoError(t, afero.WriteFile(dst, "project/extra.txt", []byte("untouched"), 0644))
+
+	diffs, err := fs.Diff(dst, ".", "project")
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 3)
+
+	assert.Equal(t, "docs/guide.md", diffs[0].Path)
+	assert.Equal(t, ChangeAdded, diffs[0].Change)
+	assert.Equal(t, "--- /dev/null\n+++ b/docs/guide.md\n@@ -0,0 +1 @@\n+# Guide\n", diffs[0].Unified)
+
+	assert.Equal(t, "main.go", diffs[1].Path)
+	assert.Equal(t, ChangeModified, diffs[1].Change)
+	assert.Equal(t, `--- a/main.go
++++ b/main.go
+@@ -1,5 +1,5 @@
+ package main
+ 
+ func main() {
+-	println("old")
++	println("new")
+ }
+`, diffs[1].Unified)
+
+	assert.Equal(t, "run.sh", diffs[2].Path)
+	assert.Equal(t, ChangeModified, diffs[2].Change)
+	assert.Equal(t, DefaultFileMode, diffs[2].OldMode)
+	assert.Equal(t, ExecFileMode, diffs[2].NewMode)
+	assert.Empty(t, diffs[2].Unified)
+}
+
+func TestSubsetWriteProject(t *testing.T) {
+	fs := NewMemoryFileSystem()
+	assert.NoError(t, fs.WriteFile("main.go", "package main"))
+	assert.NoError(t, fs.WriteFile("bin/run", "#!/bin/sh"))
+	assert.NoError(t, fs.WriteFile("go.mod", "module example"))
+
+	dst := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(dst, "project/main.go", []byte("old"), 0644))
+	assert.NoError(t, afero.WriteFile(dst, "project/go.mod", []byte("old"), 0644))
+
+	subset, err := fs.Subset([]string{"main.go", "bin/run"})
+	assert.NoError(t, err)
+	report, err := subset.WriteProject(dst, ".", "project", ExistsReview)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, report.Conflicts)
+
+	content, err := afero.ReadFile(dst, "project/main.go")
+	assert.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+
+	// Rejected changes leave the existing file untouched
+	content, err = afero.ReadFile(dst, "project/go.mod")
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+
+	info, err := dst.Stat("project/bin/run")
+	assert.NoError(t, err)
+	assert.Equal(t, ExecFileMode, info.Mode().Perm())
+}
@@ -0,0 +1,143 @@
+package fs
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled .gitignore pattern
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// GitIgnore matches slash-separated paths, relative to the repository root, against the
+// patterns of one or more .gitignore files. Later patterns take precedence over earlier ones.
+type GitIgnore struct {
+	rules []ignoreRule
+}
+
+// NewGitIgnore creates an empty matcher that ignores nothing
+func NewGitIgnore() *GitIgnore {
+	return &GitIgnore{}
+}
+
+// AddPatterns reads .gitignore patterns from r. base is the slash-separated directory,
+// relative to the repository root, containing the .gitignore file ("" for the root).
+func (g *GitIgnore) AddPatterns(r io.Reader, base string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		g.AddPattern(scanner.Text(), base)
+	}
+	return scanner.Err()
+}
+
+// AddPattern adds a single .gitignore pattern relative to the directory base
+func (g *GitIgnore) AddPattern(pattern, base string) {
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return
+	}
+
+	rule := ignoreRule{}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return
+	}
+
+	// Patterns with a slash other than a trailing one are relative to the .gitignore
+	// directory, all others match at any depth below it
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	prefix := ""
+	if base != "" && base != "." {
+		prefix = regexp.QuoteMeta(strings.Trim(base, "/")) + "/"
+	}
+	expr := "^" + prefix
+	if !anchored {
+		expr += "(?:.*/)?"
+	}
+	expr += globToRegexp(pattern) + "$"
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// Invalid patterns are ignored, as git does
+		return
+	}
+	rule.re = re
+	g.rules = append(g.rules, rule)
+}
+
+// Match reports whether the slash-separated path p is ignored
+func (g *GitIgnore) Match(p string, isDir bool) bool {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(p) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a .gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				// "**/" matches zero or more directories, a trailing "**" matches everything
+				if i+2 < len(glob) && glob[i+2] == '/' {
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
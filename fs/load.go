@@ -0,0 +1,206 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// manifestFiles lists files that describe a project's stack and are included in summaries
+var manifestFiles = []string{
+	"go.mod",
+	"package.json",
+	"Cargo.toml",
+	"pyproject.toml",
+	"requirements.txt",
+	"pom.xml",
+	"build.gradle",
+	"Gemfile",
+	"go.work",
+	"pnpm-workspace.yaml",
+}
+
+const (
+	// maxManifests is the number of manifest files included in summaries
+	maxManifests = 10
+	// maxManifestLength is the number of characters of each manifest file included in summaries
+	maxManifestLength = 2000
+)
+
+// LoadDir loads the directory root of srcFS into a new in-memory file system, skipping
+// the .git directory and everything ignored by the .gitignore files found along the way
+func LoadDir(srcFS afero.Fs, root string) (*FileSystem, error) {
+	info, err := srcFS.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("error accessing directory %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	loaded := NewMemoryFileSystem()
+	ignore := NewGitIgnore()
+
+	err = afero.Walk(srcFS, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path: %w", err)
+		}
+		slashPath := filepath.ToSlash(relPath)
+
+		if relPath != "." {
+			if info.IsDir() && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if ignore.Match(slashPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if info.IsDir() {
+			// Directories are visited before their contents, so their patterns apply to them
+			if err := loadGitIgnore(srcFS, ignore, path, slashPath); err != nil {
+				return err
+			}
+			return loaded.Fs.MkdirAll(relPath, permOrDefault(info))
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(srcFS, loaded.Fs, path, relPath, permOrDefault(info))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading directory %s: %w", root, err)
+	}
+
+	return loaded, nil
+}
+
+// loadGitIgnore adds the patterns of the .gitignore file in dir, if any, to ignore
+func loadGitIgnore(srcFS afero.Fs, ignore *GitIgnore, dir, base string) error {
+	f, err := srcFS.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening .gitignore in %s: %w", dir, err)
+	}
+	defer f.Close()
+
+	if base == "." {
+		base = ""
+	}
+	if err := ignore.AddPatterns(f, base); err != nil {
+		return fmt.Errorf("error reading .gitignore in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// Exists reports whether a file or directory exists in the file system
+func (fs *FileSystem) Exists(path string) bool {
+	exists, err := afero.Exists(fs.Fs, path)
+	return err == nil && exists
+}
+
+// Tree renders the file system as a text tree rooted at "project-root/", listing at most
+// maxEntries entries
+func (fs *FileSystem) Tree(maxEntries int) (string, error) {
+	structure, err := fs.ListFiles(".")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("project-root/\n")
+	count := 0
+	writeTree(&b, structure, "", &count, maxEntries)
+	if count > maxEntries {
+		b.WriteString(fmt.Sprintf("... (%d more entries)\n", count-maxEntries))
+	}
+	return b.String(), nil
+}
+
+// writeTree writes the entries of a directory structure returned by ListFiles, counting
+// every entry and writing the first maxEntries
+func writeTree(b *strings.Builder, structure map[string]interface{}, indent string, count *int, maxEntries int) {
+	names := make([]string, 0, len(structure))
+	for name := range structure {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		*count++
+		children, isDir := structure[name].(map[string]interface{})
+
+		connector, childIndent := "├── ", indent+"│   "
+		if i == len(names)-1 {
+			connector, childIndent = "└── ", indent+"    "
+		}
+		if *count <= maxEntries {
+			if isDir {
+				name += "/"
+			}
+			b.WriteString(indent + connector + name + "\n")
+		}
+		if isDir {
+			writeTree(b, children, childIndent, count, maxEntries)
+		}
+	}
+}
+
+// Summarize describes the file system for use in prompts: its file tree, with at most
+// maxEntries entries, followed by the contents of the manifest files found in it
+func (fs *FileSystem) Summarize(maxEntries int) (string, error) {
+	tree, err := fs.Tree(maxEntries)
+	if err != nil {
+		return "", fmt.Errorf("error summarizing file system: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("File Tree:\n")
+	b.WriteString(tree)
+
+	var manifests []string
+	err = afero.Walk(fs.Fs, ".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		for _, name := range manifestFiles {
+			if info.Name() == name && len(manifests) < maxManifests {
+				manifests = append(manifests, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error summarizing file system: %w", err)
+	}
+
+	for _, path := range manifests {
+		content, err := afero.ReadFile(fs.Fs, path)
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %w", path, err)
+		}
+		text := string(content)
+		if len(text) > maxManifestLength {
+			text = text[:maxManifestLength] + "\n... (truncated)"
+		}
+		b.WriteString(fmt.Sprintf("\n// file: %s\n%s\n", filepath.ToSlash(path), text))
+	}
+
+	return b.String(), nil
+}
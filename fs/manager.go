@@ -1,7 +1,6 @@
 package fs
 
 import (
-	"archive/zip"
 	"bytes"
 	"fmt"
 	"io"
@@ -15,6 +14,9 @@ import (
 // FileSystem wraps the Afero Fs interface
 type FileSystem struct {
 	Fs afero.Fs
+
+	// modes holds file modes set explicitly by file operations
+	modes map[string]os.FileMode
 }
 
 // NewMemoryFileSystem creates a new in-memory file system
@@ -35,6 +37,7 @@ func NewOsFileSystem() *FileSystem {
 type FileOperation struct {
 	Operation string `json:"operation"`
 	Path      string `json:"path"`
+	Mode      string `json:"mode,omitempty"`
 }
 
 // ExecuteFileOperations performs a series of file operations
@@ -51,9 +54,19 @@ func (fs *FileSystem) ExecuteFileOperations(operations []FileOperation) error {
 func (fs *FileSystem) ExecuteFileOperation(op FileOperation) error {
 	switch op.Operation {
 	case "CREATE_DIR":
-		return fs.Fs.MkdirAll(op.Path, 0755)
+		return fs.Fs.MkdirAll(op.Path, DefaultDirMode)
 	case "CREATE_FILE":
-		return fs.CreateFile(op.Path)
+		if err := fs.CreateFile(op.Path); err != nil {
+			return err
+		}
+		if op.Mode == "" {
+			return nil
+		}
+		mode, err := ParseFileMode(op.Mode)
+		if err != nil {
+			return err
+		}
+		return fs.SetFileMode(op.Path, mode)
 	default:
 		return fmt.Errorf("unknown operation: %s", op.Operation)
 	}
@@ -62,25 +75,31 @@ func (fs *FileSystem) ExecuteFileOperation(op FileOperation) error {
 // CreateFile creates a new file
 func (fs *FileSystem) CreateFile(path string) error {
 	dir := filepath.Dir(path)
-	if err := fs.Fs.MkdirAll(dir, 0755); err != nil {
+	if err := fs.Fs.MkdirAll(dir, DefaultDirMode); err != nil {
 		return fmt.Errorf("error creating directory %s: %w", dir, err)
 	}
 
-	f, err := fs.Fs.Create(path)
+	mode := fs.FileMode(path, "")
+	f, err := fs.Fs.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("error creating file %s: %w", path, err)
 	}
 	defer f.Close()
 
-	return nil
+	return fs.Fs.Chmod(path, mode)
 }
 
-// WriteFile creates a new file with the given content or overwrites an existing file with the content
+// WriteFile creates a new file with the given content or overwrites an existing file with the content.
+// The file mode is the one set by a file operation, or inferred from the path and content otherwise.
 func (fs *FileSystem) WriteFile(path string, content string) error {
-	err := afero.WriteFile(fs.Fs, path, []byte(content), 0644)
+	mode := fs.FileMode(path, content)
+	err := afero.WriteFile(fs.Fs, path, []byte(content), mode)
 	if err != nil {
 		return fmt.Errorf("error writing file %s: %w", path, err)
 	}
+	if err := fs.Fs.Chmod(path, mode); err != nil {
+		return fmt.Errorf("error setting mode of %s: %w", path, err)
+	}
 	return nil
 }
 
@@ -97,7 +116,7 @@ func (fs *FileSystem) IsDir(path string) bool {
 // TODO: This function will need to be handled differently as we can't execute git commands on the in-memory file system
 func (fs *FileSystem) InitializeGitRepo() error {
 	// For now, we'll just create a .git directory to simulate git initialization
-	return fs.Fs.MkdirAll(".git", 0755)
+	return fs.Fs.MkdirAll(".git", DefaultDirMode)
 }
 
 // CopyDir copies a directory from one file system to another
@@ -112,7 +131,7 @@ func (fs *FileSystem) CopyDir(dstFS afero.Fs, srcPath, dstPath string) error {
 	}
 
 	// Create the destination directory
-	err = dstFS.MkdirAll(dstPath, srcInfo.Mode())
+	err = dstFS.MkdirAll(dstPath, permOrDefault(srcInfo))
 	if err != nil {
 		return fmt.Errorf("error creating destination directory: %w", err)
 	}
@@ -132,23 +151,23 @@ func (fs *FileSystem) CopyDir(dstFS afero.Fs, srcPath, dstPath string) error {
 
 		if info.IsDir() {
 			// Create directory in destination
-			return dstFS.MkdirAll(dstItemPath, info.Mode())
+			return dstFS.MkdirAll(dstItemPath, permOrDefault(info))
 		} else {
 			// Copy file to destination
-			return copyFile(fs.Fs, dstFS, path, dstItemPath)
+			return copyFile(fs.Fs, dstFS, path, dstItemPath, permOrDefault(info))
 		}
 	})
 }
 
-// copyFile is a helper function to copy a single file
-func copyFile(srcFS, dstFS afero.Fs, srcPath, dstPath string) error {
+// copyFile is a helper function to copy a single file, preserving its mode
+func copyFile(srcFS, dstFS afero.Fs, srcPath, dstPath string, mode os.FileMode) error {
 	srcFile, err := srcFS.Open(srcPath)
 	if err != nil {
 		return fmt.Errorf("error opening source file: %w", err)
 	}
 	defer srcFile.Close()
 
-	dstFile, err := dstFS.Create(dstPath)
+	dstFile, err := dstFS.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("error creating destination file: %w", err)
 	}
@@ -159,69 +178,20 @@ func copyFile(srcFS, dstFS afero.Fs, srcPath, dstPath string) error {
 		return fmt.Errorf("error copying file contents: %w", err)
 	}
 
+	// The mode passed to OpenFile is subject to umask and ignored for existing files
+	if err := dstFS.Chmod(dstPath, mode); err != nil {
+		return fmt.Errorf("error setting file mode: %w", err)
+	}
+
 	return nil
 }
 
 // WriteToZip writes the in-memory file system to a zip and returns it as bytes
 func (fs *FileSystem) WriteToZip() ([]byte, error) {
 	var buf bytes.Buffer
-	zipWriter := zip.NewWriter(&buf)
-
-	fileCount := 0
-	err := afero.Walk(fs.Fs, ".", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip root directory
-		if path == "." {
-			return nil
-		}
-
-		// Use path as is, without removing leading slash
-		zipPath := path
-
-		if info.IsDir() {
-			_, err := zipWriter.Create(zipPath + "/")
-			if err != nil {
-				return fmt.Errorf("error creating zip entry for directory %s: %w", zipPath, err)
-			}
-			return nil
-		}
-
-		writer, err := zipWriter.Create(zipPath)
-		if err != nil {
-			return fmt.Errorf("error creating zip entry for file %s: %w", zipPath, err)
-		}
-
-		file, err := fs.Fs.Open(path)
-		if err != nil {
-			return fmt.Errorf("error opening file %s: %w", path, err)
-		}
-		defer file.Close()
-
-		_, err = io.Copy(writer, file)
-		if err != nil {
-			return fmt.Errorf("error writing file %s to zip: %w", path, err)
-		}
-
-		fileCount++
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error walking file system: %w", err)
+	if err := fs.WriteZip(&buf); err != nil {
+		return nil, err
 	}
-
-	if fileCount == 0 {
-		return nil, fmt.Errorf("no files to zip")
-	}
-
-	err = zipWriter.Close()
-	if err != nil {
-		return nil, fmt.Errorf("error closing zip writer: %w", err)
-	}
-
 	return buf.Bytes(), nil
 }
 
@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// DefaultFileMode is the mode given to regular generated files
+	DefaultFileMode os.FileMode = 0644
+	// ExecFileMode is the mode given to generated scripts and entrypoints
+	ExecFileMode os.FileMode = 0755
+	// DefaultDirMode is the mode given to generated directories
+	DefaultDirMode os.FileMode = 0755
+)
+
+// executableNames lists well-known file names that are expected to be executable
+var executableNames = map[string]bool{
+	"mvnw":      true,
+	"gradlew":   true,
+	"configure": true,
+	"manage.py": true,
+}
+
+// executableExts lists file extensions that are expected to be executable
+var executableExts = map[string]bool{
+	".sh":   true,
+	".bash": true,
+	".zsh":  true,
+}
+
+// InferFileMode infers the mode of a generated file from its path and content.
+// Files starting with a shebang, well-known wrapper scripts, shell scripts and
+// files directly under a bin/ directory are executable.
+func InferFileMode(path, content string) os.FileMode {
+	if strings.HasPrefix(content, "#!") {
+		return ExecFileMode
+	}
+	name := filepath.Base(path)
+	if executableNames[name] || executableExts[filepath.Ext(name)] {
+		return ExecFileMode
+	}
+	if filepath.Base(filepath.Dir(path)) == "bin" {
+		return ExecFileMode
+	}
+	return DefaultFileMode
+}
+
+// ParseFileMode parses an octal file mode such as "0755" or "755"
+func ParseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid file mode %q: %w", s, err)
+	}
+	if mode&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("invalid file mode %q: only permission bits are allowed", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// SetFileMode records an explicit mode for a file, overriding the inferred one.
+// If the file already exists its mode is updated immediately.
+func (fs *FileSystem) SetFileMode(path string, mode os.FileMode) error {
+	if fs.modes == nil {
+		fs.modes = make(map[string]os.FileMode)
+	}
+	fs.modes[filepath.Clean(path)] = mode.Perm()
+
+	exists, err := afero.Exists(fs.Fs, path)
+	if err != nil || !exists {
+		return err
+	}
+	if err := fs.Fs.Chmod(path, mode.Perm()); err != nil {
+		return fmt.Errorf("error setting mode of %s: %w", path, err)
+	}
+	return nil
+}
+
+// FileMode returns the mode a file with the given content will be written with
+func (fs *FileSystem) FileMode(path, content string) os.FileMode {
+	if mode, ok := fs.modes[filepath.Clean(path)]; ok {
+		return mode
+	}
+	return InferFileMode(path, content)
+}
+
+// permOrDefault returns the permission bits of a file, falling back to the default
+// mode for files that were created without any permission bits
+func permOrDefault(info os.FileInfo) os.FileMode {
+	if perm := info.Mode().Perm(); perm != 0 {
+		return perm
+	}
+	if info.IsDir() {
+		return DefaultDirMode
+	}
+	return DefaultFileMode
+}
@@ -0,0 +1,347 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ExistsPolicy determines what happens when the output directory already exists
+type ExistsPolicy string
+
+const (
+	// ExistsFail refuses to write into an existing, non-empty directory
+	ExistsFail ExistsPolicy = "fail"
+	// ExistsOverwrite overwrites the generated files in the existing directory. Like merge, it
+	// keeps all other files, so files the project did not generate are never deleted.
+	ExistsOverwrite ExistsPolicy = "overwrite"
+	// ExistsMerge writes the generated project into the existing directory,
+	// overwriting conflicting files and keeping all others
+	ExistsMerge ExistsPolicy = "merge"
+	// ExistsBackup moves the existing directory aside before writing the generated project
+	ExistsBackup ExistsPolicy = "backup"
+	// ExistsReview lets the user review the changes to each file, and merges only the
+	// accepted files into the existing directory
+	ExistsReview ExistsPolicy = "review"
+)
+
+// ExistsPolicies lists all valid output directory policies
+var ExistsPolicies = []ExistsPolicy{ExistsFail, ExistsOverwrite, ExistsMerge, ExistsBackup, ExistsReview}
+
+// ParseExistsPolicy parses an output directory policy name
+func ParseExistsPolicy(s string) (ExistsPolicy, error) {
+	for _, p := range ExistsPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	names := make([]string, len(ExistsPolicies))
+	for i, p := range ExistsPolicies {
+		names[i] = string(p)
+	}
+	return "", fmt.Errorf("invalid policy %q: must be one of %s", s, strings.Join(names, ", "))
+}
+
+// OutputExistsError is returned when the output directory exists and the policy forbids writing into it
+type OutputExistsError struct {
+	Path      string
+	Conflicts []string
+}
+
+func (e *OutputExistsError) Error() string {
+	if len(e.Conflicts) == 0 {
+		return fmt.Sprintf("output directory %s already exists", e.Path)
+	}
+	return fmt.Sprintf("output directory %s already exists and %d file(s) would be overwritten: %s",
+		e.Path, len(e.Conflicts), strings.Join(e.Conflicts, ", "))
+}
+
+// WriteReport describes the result of writing a project to its output directory
+type WriteReport struct {
+	// Path is the directory the project was written to
+	Path string
+	// Conflicts lists the files, relative to Path, that existed before and were overwritten
+	Conflicts []string
+	// BackupPath is where the previous directory was moved to, if it was backed up
+	BackupPath string
+}
+
+// Conflicts returns the files under srcPath that already exist under dstPath on dstFS,
+// as sorted slash-separated paths relative to srcPath
+func (fs *FileSystem) Conflicts(dstFS afero.Fs, srcPath, dstPath string) ([]string, error) {
+	var conflicts []string
+	err := afero.Walk(fs.Fs, srcPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path: %w", err)
+		}
+		dstInfo, err := dstFS.Stat(filepath.Join(dstPath, relPath))
+		if err == nil && !dstInfo.IsDir() {
+			conflicts = append(conflicts, filepath.ToSlash(relPath))
+		} else if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error checking for conflicts: %w", err)
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// CheckOutputDir verifies that a project can be written to dstPath under the given policy
+// without generating it first. It only fails for the fail policy when dstPath is a
+// non-empty directory, or when dstPath exists and is not a directory.
+func CheckOutputDir(dstFS afero.Fs, dstPath string, policy ExistsPolicy) error {
+	info, err := dstFS.Stat(dstPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error accessing output directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("output path %s exists and is not a directory", dstPath)
+	}
+	if policy != ExistsFail {
+		return nil
+	}
+	empty, err := afero.IsEmpty(dstFS, dstPath)
+	if err != nil {
+		return fmt.Errorf("error accessing output directory: %w", err)
+	}
+	if !empty {
+		return &OutputExistsError{Path: dstPath}
+	}
+	return nil
+}
+
+// EnsureWritableDir creates dir and its parents if needed and verifies that files can be
+// created in it, so that an unusable output directory is reported before generating anything
+func EnsureWritableDir(dstFS afero.Fs, dir string) error {
+	if err := dstFS.MkdirAll(dir, DefaultDirMode); err != nil {
+		return fmt.Errorf("error creating output directory %s: %w", dir, err)
+	}
+	info, err := dstFS.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("error accessing output directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("output path %s is not a directory", dir)
+	}
+	f, err := afero.TempFile(dstFS, dir, ".boil-write-check-")
+	if err != nil {
+		return fmt.Errorf("output directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	if err := dstFS.Remove(name); err != nil {
+		return fmt.Errorf("error cleaning up output directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// WriteProject writes the directory srcPath to dstPath on dstFS according to the policy.
+// The project is first staged in a temporary sibling directory of dstPath and then
+// renamed into place, so an interrupted write never leaves a partially written project.
+// When overwriting or merging into an existing directory, only the generated files are
+// staged and moved over it, and the moves are undone if one fails.
+func (fs *FileSystem) WriteProject(dstFS afero.Fs, srcPath, dstPath string, policy ExistsPolicy) (*WriteReport, error) {
+	dstPath = filepath.Clean(dstPath)
+	report := &WriteReport{Path: dstPath}
+
+	if err := CheckOutputDir(dstFS, dstPath, policy); err != nil {
+		return nil, err
+	}
+	exists, err := afero.DirExists(dstFS, dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("error accessing output directory: %w", err)
+	}
+	if exists {
+		report.Conflicts, err = fs.Conflicts(dstFS, srcPath, dstPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	parent := filepath.Dir(dstPath)
+	if err := dstFS.MkdirAll(parent, DefaultDirMode); err != nil {
+		return nil, fmt.Errorf("error creating parent directory %s: %w", parent, err)
+	}
+	stagingPath, err := afero.TempDir(dstFS, parent, "."+filepath.Base(dstPath)+".boil-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating staging directory: %w", err)
+	}
+	// The staging directory is renamed on success, so this only cleans up after failures
+	defer dstFS.RemoveAll(stagingPath)
+
+	if exists && policy != ExistsBackup {
+		// Only the generated files are staged, and moved over the existing tree
+		newPath := filepath.Join(stagingPath, "new")
+		if err := fs.CopyDir(dstFS, srcPath, newPath); err != nil {
+			return nil, fmt.Errorf("error staging project: %w", err)
+		}
+		if err := mergeStaged(dstFS, newPath, filepath.Join(stagingPath, "old"), dstPath); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+	if err := fs.CopyDir(dstFS, srcPath, stagingPath); err != nil {
+		return nil, fmt.Errorf("error staging project: %w", err)
+	}
+
+	if !exists {
+		if err := dstFS.Rename(stagingPath, dstPath); err != nil {
+			return nil, fmt.Errorf("error moving project into place: %w", err)
+		}
+		return report, nil
+	}
+
+	// Move the existing directory aside, then swap the staged project in
+	asidePath, err := backupPath(dstFS, dstPath, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	report.BackupPath = asidePath
+	if err := dstFS.Rename(dstPath, asidePath); err != nil {
+		return nil, fmt.Errorf("error moving existing directory aside: %w", err)
+	}
+	if err := dstFS.Rename(stagingPath, dstPath); err != nil {
+		// Put the original directory back so nothing is lost
+		if restoreErr := dstFS.Rename(asidePath, dstPath); restoreErr != nil {
+			return nil, fmt.Errorf("error moving project into place: %w (previous directory left at %s)", err, asidePath)
+		}
+		return nil, fmt.Errorf("error moving project into place: %w", err)
+	}
+	return report, nil
+}
+
+// backupPath returns an unused path to move dstPath aside to, named after the time with a
+// counter for backups made in the same second
+func backupPath(dstFS afero.Fs, dstPath string, now time.Time) (string, error) {
+	base := fmt.Sprintf("%s.bak-%s", dstPath, now.Format("20060102-150405"))
+	path := base
+	for i := 2; ; i++ {
+		if _, err := dstFS.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		} else if err != nil {
+			return "", fmt.Errorf("error accessing backup path %s: %w", path, err)
+		}
+		path = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// move is a rename done while merging, undone by renaming back
+type move struct {
+	from, to string
+}
+
+// mergeStaged moves the files staged in newPath into dstPath. The files they replace are
+// first moved to oldPath. If a move fails, the moves done so far are undone, so dstPath is
+// either fully updated or left as it was. Other files in dstPath, such as symlinks or large
+// directories, are neither copied nor touched.
+func mergeStaged(dstFS afero.Fs, newPath, oldPath, dstPath string) (err error) {
+	var moves []move
+	var created []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(moves) - 1; i >= 0; i-- {
+			if undoErr := dstFS.Rename(moves[i].to, moves[i].from); undoErr != nil {
+				err = fmt.Errorf("%w (restoring %s failed: %v)", err, moves[i].from, undoErr)
+			}
+		}
+		for i := len(created) - 1; i >= 0; i-- {
+			dstFS.Remove(created[i])
+		}
+	}()
+
+	rename := func(from, to string) error {
+		if err := dstFS.Rename(from, to); err != nil {
+			return err
+		}
+		moves = append(moves, move{from: from, to: to})
+		return nil
+	}
+
+	var files []string
+	walkErr := afero.Walk(dstFS, newPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(newPath, path)
+		if err != nil {
+			return fmt.Errorf("error calculating relative path: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, relPath)
+			return nil
+		}
+		target := filepath.Join(dstPath, relPath)
+		targetInfo, err := dstFS.Stat(target)
+		if os.IsNotExist(err) {
+			if err := dstFS.Mkdir(target, permOrDefault(info)); err != nil {
+				return fmt.Errorf("error creating directory %s: %w", target, err)
+			}
+			created = append(created, target)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error accessing %s: %w", target, err)
+		}
+		if !targetInfo.IsDir() {
+			return fmt.Errorf("cannot replace file %s with a directory", target)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	for _, relPath := range files {
+		target := filepath.Join(dstPath, relPath)
+		targetInfo, err := lstat(dstFS, target)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return fmt.Errorf("error accessing %s: %w", target, err)
+		case targetInfo.IsDir():
+			return fmt.Errorf("cannot replace directory %s with a file", target)
+		default:
+			old := filepath.Join(oldPath, relPath)
+			if err := dstFS.MkdirAll(filepath.Dir(old), DefaultDirMode); err != nil {
+				return fmt.Errorf("error staging %s: %w", target, err)
+			}
+			if err := rename(target, old); err != nil {
+				return fmt.Errorf("error moving %s aside: %w", target, err)
+			}
+		}
+		if err := rename(filepath.Join(newPath, relPath), target); err != nil {
+			return fmt.Errorf("error moving %s into place: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// lstat returns the file info of a path without following a final symlink, if the file
+// system supports it
+func lstat(fsys afero.Fs, path string) (os.FileInfo, error) {
+	if l, ok := fsys.(afero.Lstater); ok {
+		info, _, err := l.LstatIfPossible(path)
+		return info, err
+	}
+	return fsys.Stat(path)
+}
@@ -72,10 +72,48 @@ func NewAnthropicClient(cfg *LlmConfig, logger logger.Logger) (LlmClient, error)
 }
 
 func (a *AnthropicClient) GetCompletion(prompt, responseType string) (string, error) {
+	system, err := getSystemPrompt(a.config.Conventions)
+	if err != nil {
+		return "", err
+	}
+	reserved, err := a.config.waitForRateLimit(system + prompt)
+	if err != nil {
+		return "", err
+	}
+	anthropicResp, err := a.send(system, prompt)
+	if err != nil {
+		a.config.releaseRateLimit(reserved)
+		return "", err
+	}
+
+	a.config.recordUsage(reserved, anthropicResp.Usage.InputTokens, anthropicResp.Usage.OutputTokens)
+
+	if len(anthropicResp.Content) == 0 {
+		return "", fmt.Errorf("no content returned from Anthropic")
+	}
+
+	res := anthropicResp.Content[0].Text
+
+	if responseType == "json_object" {
+		if !json.Valid([]byte(res)) {
+			return "", fmt.Errorf("%w from Anthropic", errInvalidJSON)
+		}
+	}
+
+	err = a.tellmClient.Log(a.config.BatchID, prompt, res, a.config.ModelName, anthropicResp.Usage.InputTokens, anthropicResp.Usage.OutputTokens)
+	if err != nil {
+		a.logger.WithField("warning", err).Warn("failed to log to tellm")
+	}
+
+	return res, nil
+}
+
+// send sends a completion request to the Anthropic API and returns its response
+func (a *AnthropicClient) send(system, prompt string) (*AnthropicResponse, error) {
 	req := AnthropicRequest{
 		Model:     a.config.ModelName,
 		MaxTokens: 2048,
-		System:    getSystemPrompt(),
+		System:    system,
 		Messages: []Message{
 			{Role: "user", Content: prompt},
 		},
@@ -83,12 +121,12 @@ func (a *AnthropicClient) GetCompletion(prompt, responseType string) (string, er
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %v", err)
+		return nil, fmt.Errorf("error marshaling request: %v", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(a.config.context(), "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	httpReq.Header.Set("x-api-key", a.config.APIKey)
@@ -97,44 +135,30 @@ func (a *AnthropicClient) GetCompletion(prompt, responseType string) (string, er
 
 	resp, err := a.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %v", err)
+		return nil, fmt.Errorf("error reading response body: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		var errResp AnthropicErrorResponse
 		if err := json.Unmarshal(body, &errResp); err != nil {
-			return "", fmt.Errorf("error unmarshaling error response: %v", err)
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("error unmarshaling error response: %v", err)}
+		}
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Type:       errResp.Error.Type,
+			Message:    fmt.Sprintf("anthropic API error: %s - %s", errResp.Error.Type, errResp.Error.Message),
 		}
-		return "", fmt.Errorf("anthropic API error: %s - %s", errResp.Error.Type, errResp.Error.Message)
 	}
 
 	var anthropicResp AnthropicResponse
 	if err := json.Unmarshal(body, &anthropicResp); err != nil {
-		return "", fmt.Errorf("error unmarshaling response: %v", err)
-	}
-
-	if len(anthropicResp.Content) == 0 {
-		return "", fmt.Errorf("no content returned from Anthropic")
+		return nil, fmt.Errorf("error unmarshaling response: %v", err)
 	}
-
-	res := anthropicResp.Content[0].Text
-
-	if responseType == "json_object" {
-		if !json.Valid([]byte(res)) {
-			return "", fmt.Errorf("invalid JSON response from Anthropic")
-		}
-	}
-
-	err = a.tellmClient.Log(a.config.BatchID, prompt, res, a.config.ModelName, anthropicResp.Usage.InputTokens, anthropicResp.Usage.OutputTokens)
-	if err != nil {
-		a.logger.WithField("warning", err).Warn("failed to log to tellm")
-	}
-
-	return res, nil
+	return &anthropicResp, nil
 }
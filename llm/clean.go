@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxPreambleLines and maxPreambleLength bound the chatty text removed before a fence
+	maxPreambleLines  = 3
+	maxPreambleLength = 300
+	// maxEpilogueLines and maxEpilogueLength bound the chatty text removed after a fence
+	maxEpilogueLines  = 5
+	maxEpilogueLength = 600
+)
+
+var (
+	// fencePattern matches markdown code fence lines, capturing the info string
+	fencePattern = regexp.MustCompile("^\\s*(?:```+|~~~+)\\s*([^`\\s]*)\\s*$")
+	// chattyPattern matches lines that introduce a response rather than being part of it
+	chattyPattern = regexp.MustCompile(`(?i)^(?:sure|certainly|of course|okay|ok|absolutely|great)\b|^(?:here(?:'s| is| are)|below is|the following is)\b`)
+)
+
+// CleanResponse removes markdown code fences, introductory preambles such as "Here is the
+// file:" and closing remarks from a completion, but only when they clearly surround the
+// whole response:
+//
+//   - a fenced block is unwrapped if it starts after a short chatty preamble and ends the
+//     response, apart from a short epilogue; fences inside it must come in pairs
+//   - an opening fence without a closing one, as in truncated responses, is removed with
+//     its preamble
+//   - without fences, a single chatty first line ending with ":" is removed
+//
+// Anything else, such as markdown files that contain code blocks, is returned unchanged.
+func CleanResponse(content string) string {
+	trimmed := strings.TrimSpace(content)
+	lines := strings.Split(trimmed, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+
+	var fences []int
+	for i, line := range lines {
+		if fencePattern.MatchString(line) {
+			fences = append(fences, i)
+		}
+	}
+
+	if len(fences) == 0 {
+		if len(lines) > 1 && isChatty(lines[0]) && strings.HasSuffix(strings.TrimSpace(lines[0]), ":") {
+			return joinLines(lines[1:])
+		}
+		return content
+	}
+
+	first, last := fences[0], fences[len(fences)-1]
+	if !isPreamble(lines[:first]) {
+		return content
+	}
+
+	if len(fences) == 1 {
+		return joinLines(lines[first+1:])
+	}
+
+	// The closing fence has no info string, and the fences inside come in pairs that each
+	// close without one
+	if fenceInfo(lines[last]) != "" || (len(fences)-2)%2 != 0 {
+		return content
+	}
+	for i := 2; i < len(fences)-1; i += 2 {
+		if fenceInfo(lines[fences[i]]) != "" {
+			return content
+		}
+	}
+	if !isEpilogue(lines[last+1:]) {
+		return content
+	}
+	return joinLines(lines[first+1 : last])
+}
+
+// cleanFile cleans the completion of a file's content. The fences of markdown files such as
+// READMEs are usually their own code blocks, so a markdown file is only unwrapped from a
+// fence with no language or markdown's, which holds the whole document.
+func cleanFile(fileName, content string) string {
+	if isMarkdown(fileName) && !wrapsDocument(content) {
+		return content
+	}
+	return CleanResponse(content)
+}
+
+// wrapsDocument reports whether the first fence of a completion, if any, could hold a
+// whole markdown document rather than one of its code blocks
+func wrapsDocument(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if fencePattern.MatchString(line) {
+			switch strings.ToLower(fenceInfo(line)) {
+			case "", "markdown", "md":
+				return true
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// fenceInfo returns the info string of a fence line, such as its language
+func fenceInfo(line string) string {
+	return fencePattern.FindStringSubmatch(line)[1]
+}
+
+// isMarkdown reports whether a file is a markdown document
+func isMarkdown(fileName string) bool {
+	base := strings.ToLower(path.Base(fileName))
+	switch path.Ext(base) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	}
+	return strings.TrimSuffix(base, path.Ext(base)) == "readme"
+}
+
+// isPreamble reports whether the lines before a fence are a short introduction. Every line
+// must be chatty: a heading or a line such as "To run the server:" is part of a document.
+func isPreamble(lines []string) bool {
+	text := nonEmpty(lines)
+	if len(text) > maxPreambleLines || len(strings.Join(text, "\n")) > maxPreambleLength {
+		return false
+	}
+	for _, line := range text {
+		if isHeading(line) || !isChatty(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEpilogue reports whether the lines after a closing fence are short closing remarks.
+// Markdown headings are not, as they mean the fence was part of a markdown document.
+func isEpilogue(lines []string) bool {
+	text := nonEmpty(lines)
+	if len(text) > maxEpilogueLines || len(strings.Join(text, "\n")) > maxEpilogueLength {
+		return false
+	}
+	for _, line := range text {
+		if isHeading(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHeading reports whether a line is a markdown heading
+func isHeading(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+// isChatty reports whether a line addresses the reader instead of being content
+func isChatty(line string) bool {
+	return chattyPattern.MatchString(strings.TrimSpace(line))
+}
+
+// nonEmpty returns the lines that are not blank
+func nonEmpty(lines []string) []string {
+	var text []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			text = append(text, line)
+		}
+	}
+	return text
+}
+
+// joinLines joins lines into content without surrounding blank lines, ending with a newline
+func joinLines(lines []string) string {
+	content := strings.Trim(strings.Join(lines, "\n"), "\n")
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return content + "\n"
+}
@@ -0,0 +1,174 @@
+package llm
+
+import "testing"
+
+func TestCleanResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected string
+	}{
+		{
+			name:     "plain content",
+			response: "package main\n\nfunc main() {}\n",
+			expected: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "fenced with language",
+			response: "```go\npackage main\n\nfunc main() {}\n```",
+			expected: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "fenced without language",
+			response: "```\nFROM golang:1.22\nRUN go build\n```\n",
+			expected: "FROM golang:1.22\nRUN go build\n",
+		},
+		{
+			name:     "tilde fence",
+			response: "~~~python\nprint('hi')\n~~~",
+			expected: "print('hi')\n",
+		},
+		{
+			name:     "surrounding whitespace",
+			response: "\n\n  ```json\n{\"name\": \"app\"}\n```  \n\n",
+			expected: "{\"name\": \"app\"}\n",
+		},
+		{
+			name:     "windows line endings",
+			response: "```yaml\r\nkey: value\r\n```\r\n",
+			expected: "key: value\n",
+		},
+		{
+			name:     "preamble and fence",
+			response: "Here is the content of `main.go`:\n\n```go\npackage main\n```",
+			expected: "package main\n",
+		},
+		{
+			name:     "chatty preamble without colon",
+			response: "Sure! This is the Dockerfile.\n```dockerfile\nFROM alpine\n```",
+			expected: "FROM alpine\n",
+		},
+		{
+			name:     "preamble, fence and epilogue",
+			response: "Certainly, here's the file:\n```js\nmodule.exports = {};\n```\nThis exports an empty object. Let me know if you need changes!",
+			expected: "module.exports = {};\n",
+		},
+		{
+			name:     "epilogue only",
+			response: "```sh\n#!/bin/sh\necho hi\n```\n\nMake the script executable with chmod +x.",
+			expected: "#!/bin/sh\necho hi\n",
+		},
+		{
+			name:     "wrapped markdown with inner blocks",
+			response: "```markdown\n# App\n\nInstall:\n\n```bash\nnpm install\n```\n\nRun:\n\n```\nnpm start\n```\n```",
+			expected: "# App\n\nInstall:\n\n```bash\nnpm install\n```\n\nRun:\n\n```\nnpm start\n```\n",
+		},
+		{
+			name:     "truncated response",
+			response: "```go\npackage main\n\nfunc main() {",
+			expected: "package main\n\nfunc main() {\n",
+		},
+		{
+			name:     "preamble without fence",
+			response: "Here is the .gitignore file:\nnode_modules/\n.env\n",
+			expected: "node_modules/\n.env\n",
+		},
+		{
+			name:     "markdown with code blocks",
+			response: "# App\n\n```bash\nnpm install\n```\n\nThen run the app.\n",
+			expected: "# App\n\n```bash\nnpm install\n```\n\nThen run the app.\n",
+		},
+		{
+			name:     "markdown starting with code block",
+			response: "```bash\nnpm install\n```\n\n## Usage\n\nRun it.\n",
+			expected: "```bash\nnpm install\n```\n\n## Usage\n\nRun it.\n",
+		},
+		{
+			name:     "code containing fences",
+			response: "package docs\n\nconst Example = `\n```go\nfmt.Println()\n```\n`\n",
+			expected: "package docs\n\nconst Example = `\n```go\nfmt.Println()\n```\n`\n",
+		},
+		{
+			name:     "long preamble is content",
+			response: "First paragraph of a document that is long.\nSecond line of it.\nThird line of it.\nFourth line:\n```\ncode\n```",
+			expected: "First paragraph of a document that is long.\nSecond line of it.\nThird line of it.\nFourth line:\n```\ncode\n```",
+		},
+		{
+			name:     "long epilogue is content",
+			response: "```\ncode\n```\none\ntwo\nthree\nfour\nfive\nsix\n",
+			expected: "```\ncode\n```\none\ntwo\nthree\nfour\nfive\nsix\n",
+		},
+		{
+			name:     "closing fence with language",
+			response: "```go\na\n```\n```go\nb\n```go",
+			expected: "```go\na\n```\n```go\nb\n```go",
+		},
+		{
+			name:     "unpaired inner fence",
+			response: "```\na\n```go\nb\n```",
+			expected: "```\na\n```go\nb\n```",
+		},
+		{
+			name:     "separate code blocks",
+			response: "```\nmake\n```\nThen:\n```go\nrun()\n```",
+			expected: "```\nmake\n```\nThen:\n```go\nrun()\n```",
+		},
+		{
+			name:     "yaml ending with colon is not a preamble",
+			response: "services:\n  web:\n    image: nginx\n",
+			expected: "services:\n  web:\n    image: nginx\n",
+		},
+		{
+			name:     "chatty first line without colon is content",
+			response: "Okay so this is the README\nMore text\n",
+			expected: "Okay so this is the README\nMore text\n",
+		},
+		{
+			name:     "markdown with a heading before a code block",
+			response: "# MyApp\n\nInstall it with:\n\n```bash\ngo install example.com/myapp@latest\n```\n\nLicensed under MIT.\n",
+			expected: "# MyApp\n\nInstall it with:\n\n```bash\ngo install example.com/myapp@latest\n```\n\nLicensed under MIT.\n",
+		},
+		{
+			name:     "instructions before a code block",
+			response: "To run the server:\n```\n./run.sh\n```\n",
+			expected: "To run the server:\n```\n./run.sh\n```\n",
+		},
+		{
+			name:     "empty fence",
+			response: "```\n```",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CleanResponse(tt.response); got != tt.expected {
+				t.Errorf("CleanResponse(%q) = %q, expected %q", tt.response, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCleanFile(t *testing.T) {
+	fenced := "```go\npackage main\n```"
+	if got := cleanFile("main.go", fenced); got != "package main\n" {
+		t.Errorf("cleanFile(main.go) = %q", got)
+	}
+	readme := "To run the server:\n```\n./run.sh\n```\n"
+	for _, name := range []string{"README.md", "docs/guide.md", "README", "notes.markdown"} {
+		if got := cleanFile(name, "```bash\nmake\n```"); got != "```bash\nmake\n```" {
+			t.Errorf("cleanFile(%s) = %q, expected it unchanged", name, got)
+		}
+	}
+	wrapped := "Here is the README:\n```markdown\n# API\n\nRun it:\n\n```bash\nmake\n```\n```"
+	if got := cleanFile("README.md", wrapped); got != "# API\n\nRun it:\n\n```bash\nmake\n```\n" {
+		t.Errorf("cleanFile(README.md) = %q, expected the outer fence removed", got)
+	}
+	blocks := "```bash\nmake\n```\n\nThen:\n\n```\n./run.sh\n```"
+	if got := cleanFile("README.md", blocks); got != blocks {
+		t.Errorf("cleanFile(README.md) = %q, expected it unchanged", got)
+	}
+	if got := cleanFile("README.md", readme); got != readme {
+		t.Errorf("cleanFile(README.md) = %q, expected it unchanged", got)
+	}
+}
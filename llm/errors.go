@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// errInvalidJSON is returned when a model is asked for a JSON object and responds with
+// something else
+var errInvalidJSON = errors.New("invalid JSON response")
+
+// APIError is an error response of a provider's API
+type APIError struct {
+	StatusCode int
+	// Type is the provider's type or code of the error, such as model_not_found
+	Type    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Retryable reports whether another model could serve the completion: the provider is rate
+// limited, overloaded or failing, or the model is unavailable. Errors of the request itself,
+// such as an invalid API key, would fail with any model of the provider.
+func (e *APIError) Retryable() bool {
+	switch e.Type {
+	case "model_not_found", "not_found_error", "rate_limit_error", "overloaded_error", "api_error":
+		return true
+	}
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests, e.StatusCode == http.StatusRequestTimeout:
+		return true
+	case e.StatusCode == http.StatusNotFound:
+		// The endpoints are fixed, so it is the model that was not found
+		return true
+	default:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+}
+
+// IsRetryable reports whether a completion that failed with err could be served by another
+// model. Completions whose context was cancelled or ran out of time are not, as every model
+// would fail the same way.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, errInvalidJSON) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	// Timeouts of the provider and unreachable providers
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
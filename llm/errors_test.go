@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timeoutError is a network timeout, such as a dial timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &APIError{StatusCode: 429}, true},
+		{"server error", &APIError{StatusCode: 502}, true},
+		{"overloaded", &APIError{StatusCode: 529, Type: "overloaded_error"}, true},
+		{"model not found", &APIError{StatusCode: 404, Type: "model_not_found"}, true},
+		{"timeout", fmt.Errorf("error sending request: %w", &net.OpError{Op: "dial", Err: timeoutError{}}), true},
+		{"invalid JSON", fmt.Errorf("%w from openai/gpt-4", errInvalidJSON), true},
+		{"unauthorized", &APIError{StatusCode: 401, Type: "authentication_error"}, false},
+		{"bad request", &APIError{StatusCode: 400, Type: "invalid_request_error"}, false},
+		{"cancelled", fmt.Errorf("error calling OpenAI API: %w", context.Canceled), false},
+		{"deadline exceeded", fmt.Errorf("error sending request: %w", context.DeadlineExceeded), false},
+		{"other", errors.New("no choices returned from OpenAI"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
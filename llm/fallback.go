@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/santiagomed/boil/logger"
+)
+
+// jsonInstruction is added to JSON prompts sent to models without a native JSON mode
+const jsonInstruction = "\n\nRespond with a single valid JSON object and nothing else."
+
+// noJSONModeModels lists the OpenAI models that don't support the JSON response format
+var noJSONModeModels = map[string]bool{
+	"gpt-4":   true,
+	"o1-mini": true,
+}
+
+// SupportsJSONMode reports whether a model of a provider can be asked for a JSON object
+// response natively, rather than only through the prompt
+func SupportsJSONMode(provider, model string) bool {
+	switch provider {
+	case ProviderOpenAI, "":
+		return !noJSONModeModels[model]
+	default:
+		return false
+	}
+}
+
+// ParseModelRef parses a model reference of the form provider/model, or model for a model of
+// defaultProvider
+func ParseModelRef(ref, defaultProvider string) (provider, model string, err error) {
+	provider, model, ok := strings.Cut(ref, "/")
+	if !ok {
+		provider, model = defaultProvider, ref
+	}
+	if !IsKnownProvider(provider) {
+		return "", "", fmt.Errorf("unknown provider %q in %q (supported providers: %s)", provider, ref, strings.Join(Providers(), ", "))
+	}
+	if model == "" {
+		return "", "", fmt.Errorf("missing model in %q", ref)
+	}
+	return provider, model, nil
+}
+
+// FallbackTarget is a model a FallbackClient sends completions to
+type FallbackTarget struct {
+	Provider string
+	Model    string
+	Client   LlmClient
+}
+
+func (t FallbackTarget) String() string {
+	return t.Provider + "/" + t.Model
+}
+
+// Served records which target of a FallbackClient served a completion
+type Served struct {
+	Provider string
+	Model    string
+	// Failures are the errors of the targets tried before it, in order
+	Failures []string
+}
+
+// FallbackClient sends completions to an ordered list of targets, moving to the next target
+// when one fails with an error another model could avoid, for example when a provider is
+// down or a model was retired. Other errors, such as an invalid API key, are returned right
+// away. JSON responses of models without a native JSON mode are asked for in the prompt and
+// validated.
+type FallbackClient struct {
+	targets []FallbackTarget
+	logger  logger.Logger
+	// OnServed, if set, is called with the target that served every completion
+	OnServed func(served Served)
+
+	mu     sync.Mutex
+	served []Served
+}
+
+// NewFallbackClient creates a client that tries targets in order
+func NewFallbackClient(targets []FallbackTarget, logger logger.Logger) (*FallbackClient, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("at least one model is required")
+	}
+	return &FallbackClient{targets: targets, logger: logger}, nil
+}
+
+func (c *FallbackClient) GetCompletion(prompt, responseType string) (string, error) {
+	var failures []string
+	for i, target := range c.targets {
+		if i > 0 {
+			c.logger.Warn(fmt.Sprintf("Falling back to %s after %s failed: %s", target, c.targets[i-1], failures[i-1]))
+		}
+		res, err := complete(target, prompt, responseType)
+		if err != nil && !IsRetryable(err) {
+			return "", fmt.Errorf("%s: %w", target, err)
+		}
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		served := Served{Provider: target.Provider, Model: target.Model, Failures: failures}
+		c.mu.Lock()
+		c.served = append(c.served, served)
+		c.mu.Unlock()
+		if c.OnServed != nil {
+			c.OnServed(served)
+		}
+		return res, nil
+	}
+	return "", fmt.Errorf("all models failed: %s", describeFailures(c.targets, failures))
+}
+
+// Served returns the targets that served the client's completions, in order
+func (c *FallbackClient) Served() []Served {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Served(nil), c.served...)
+}
+
+// complete gets a completion from a target, asking for JSON in the prompt and validating the
+// response if the target has no native JSON mode
+func complete(target FallbackTarget, prompt, responseType string) (string, error) {
+	if responseType != "json_object" || SupportsJSONMode(target.Provider, target.Model) {
+		return target.Client.GetCompletion(prompt, responseType)
+	}
+	res, err := target.Client.GetCompletion(prompt+jsonInstruction, "text")
+	if err != nil {
+		return "", err
+	}
+	res = CleanResponse(res)
+	if !json.Valid([]byte(res)) {
+		return "", fmt.Errorf("%w from %s", errInvalidJSON, target)
+	}
+	return res, nil
+}
+
+// describeFailures lists the error of each target
+func describeFailures(targets []FallbackTarget, failures []string) string {
+	descriptions := make([]string, len(failures))
+	for i, failure := range failures {
+		descriptions[i] = fmt.Sprintf("%s: %s", targets[i], failure)
+	}
+	return strings.Join(descriptions, "; ")
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/santiagomed/boil/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient returns a fixed completion or error and records the calls it gets
+type fakeClient struct {
+	res   string
+	err   error
+	calls []string
+}
+
+func (c *fakeClient) GetCompletion(prompt, responseType string) (string, error) {
+	c.calls = append(c.calls, responseType+": "+prompt)
+	return c.res, c.err
+}
+
+func TestFallbackClient(t *testing.T) {
+	t.Run("falls back on errors", func(t *testing.T) {
+		primary := &fakeClient{err: &APIError{StatusCode: 500, Message: "OpenAI server error"}}
+		secondary := &fakeClient{res: "Output"}
+		client, err := NewFallbackClient([]FallbackTarget{
+			{Provider: ProviderOpenAI, Model: "gpt-4o", Client: primary},
+			{Provider: ProviderOpenAI, Model: "gpt-4o-mini", Client: secondary},
+		}, logger.NewNullLogger())
+		require.NoError(t, err)
+		var served []Served
+		client.OnServed = func(s Served) { served = append(served, s) }
+
+		res, err := client.GetCompletion("prompt", "text")
+		require.NoError(t, err)
+		assert.Equal(t, "Output", res)
+		assert.Equal(t, []Served{{Provider: ProviderOpenAI, Model: "gpt-4o-mini", Failures: []string{"OpenAI server error"}}}, served)
+		assert.Equal(t, served, client.Served())
+		assert.Len(t, primary.calls, 1)
+	})
+
+	t.Run("validates JSON of models without a JSON mode", func(t *testing.T) {
+		primary := &fakeClient{err: &APIError{StatusCode: 404, Type: "model_not_found", Message: "model_not_found"}}
+		chatty := &fakeClient{res: "Here are the files: main.go"}
+		anthropic := &fakeClient{res: "```json\n{\"files\": [\"main.go\"]}\n```"}
+		client, err := NewFallbackClient([]FallbackTarget{
+			{Provider: ProviderOpenAI, Model: "gpt-4o", Client: primary},
+			{Provider: ProviderOpenAI, Model: "gpt-4", Client: chatty},
+			{Provider: ProviderAnthropic, Model: "claude-3-5-haiku-latest", Client: anthropic},
+		}, logger.NewNullLogger())
+		require.NoError(t, err)
+
+		res, err := client.GetCompletion("List the files", "json_object")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"files": ["main.go"]}`, res)
+		assert.Equal(t, []string{"json_object: List the files"}, primary.calls)
+		require.Len(t, anthropic.calls, 1)
+		assert.True(t, strings.HasPrefix(anthropic.calls[0], "text: List the files"))
+		assert.Contains(t, anthropic.calls[0], "JSON object")
+		assert.Equal(t, []string{"model_not_found", "invalid JSON response from openai/gpt-4"}, client.Served()[0].Failures)
+	})
+
+	t.Run("fails when every model fails", func(t *testing.T) {
+		client, err := NewFallbackClient([]FallbackTarget{
+			{Provider: ProviderOpenAI, Model: "gpt-4o", Client: &fakeClient{err: &APIError{StatusCode: 503, Message: "down"}}},
+			{Provider: ProviderAnthropic, Model: "claude-3-5-haiku-latest", Client: &fakeClient{err: &APIError{StatusCode: 529, Type: "overloaded_error", Message: "overloaded"}}},
+		}, logger.NewNullLogger())
+		require.NoError(t, err)
+		_, err = client.GetCompletion("prompt", "text")
+		assert.EqualError(t, err, "all models failed: openai/gpt-4o: down; anthropic/claude-3-5-haiku-latest: overloaded")
+		assert.Empty(t, client.Served())
+	})
+
+	t.Run("returns errors other models would get", func(t *testing.T) {
+		primary := &fakeClient{err: &APIError{StatusCode: 401, Type: "invalid_api_key", Message: "unauthorized: invalid OpenAI API key"}}
+		secondary := &fakeClient{res: "Output"}
+		client, err := NewFallbackClient([]FallbackTarget{
+			{Provider: ProviderOpenAI, Model: "gpt-4o", Client: primary},
+			{Provider: ProviderOpenAI, Model: "gpt-4o-mini", Client: secondary},
+		}, logger.NewNullLogger())
+		require.NoError(t, err)
+
+		_, err = client.GetCompletion("prompt", "text")
+		assert.EqualError(t, err, "openai/gpt-4o: unauthorized: invalid OpenAI API key")
+		var apiErr *APIError
+		assert.ErrorAs(t, err, &apiErr)
+		assert.Len(t, primary.calls, 1)
+		assert.Empty(t, secondary.calls)
+		assert.Empty(t, client.Served())
+	})
+
+	t.Run("stops when the request runs out of time", func(t *testing.T) {
+		primary := &fakeClient{err: fmt.Errorf("error sending request: %w", context.DeadlineExceeded)}
+		secondary := &fakeClient{res: "Output"}
+		client, err := NewFallbackClient([]FallbackTarget{
+			{Provider: ProviderAnthropic, Model: "claude-3-5-sonnet-latest", Client: primary},
+			{Provider: ProviderOpenAI, Model: "gpt-4o-mini", Client: secondary},
+		}, logger.NewNullLogger())
+		require.NoError(t, err)
+
+		_, err = client.GetCompletion("prompt", "text")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, secondary.calls)
+	})
+
+	_, err := NewFallbackClient(nil, logger.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestParseModelRef(t *testing.T) {
+	provider, model, err := ParseModelRef("anthropic/claude-3-5-haiku-latest", ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderAnthropic, provider)
+	assert.Equal(t, "claude-3-5-haiku-latest", model)
+
+	provider, model, err = ParseModelRef("gpt-4o-mini", ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderOpenAI, provider)
+	assert.Equal(t, "gpt-4o-mini", model)
+
+	_, _, err = ParseModelRef("acme/big", ProviderOpenAI)
+	assert.ErrorContains(t, err, `unknown provider "acme"`)
+	_, _, err = ParseModelRef("openai/", ProviderOpenAI)
+	assert.Error(t, err)
+
+	assert.True(t, SupportsJSONMode(ProviderOpenAI, "gpt-4o"))
+	assert.False(t, SupportsJSONMode(ProviderOpenAI, "gpt-4"))
+	assert.False(t, SupportsJSONMode(ProviderAnthropic, "claude-3-5-sonnet-latest"))
+}
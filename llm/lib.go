@@ -1,9 +1,11 @@
 package llm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/santiagomed/boil/fs"
 )
@@ -13,23 +15,98 @@ type LlmConfig struct {
 	ModelName string
 	BatchID   string
 	TellmURL  string
+	// Conventions are added to the system prompt of every completion
+	Conventions string
+	// OnUsage, if set, is called with the tokens used by every completion
+	OnUsage func(model string, inputTokens, outputTokens int)
+	// RateLimiter, if set, delays completions to stay within the limits of the API key
+	RateLimiter *RateLimiter
+	// OnRateLimitWait, if set, is called when a completion waited for the RateLimiter
+	OnRateLimitWait func(wait time.Duration)
+	// Context, if set, cancels completions, including their waits for the RateLimiter
+	Context context.Context
+}
+
+// context returns the context of completions
+func (c *LlmConfig) context() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+// waitForRateLimit waits until the RateLimiter allows a completion of prompt and returns the
+// tokens reserved for it
+func (c *LlmConfig) waitForRateLimit(prompt string) (int, error) {
+	if c.RateLimiter == nil {
+		return 0, nil
+	}
+	reserved := estimateTokens(prompt)
+	wait, err := c.RateLimiter.Wait(c.context(), reserved)
+	if err != nil {
+		return 0, err
+	}
+	if wait > 0 && c.OnRateLimitWait != nil {
+		c.OnRateLimitWait(wait)
+	}
+	return reserved, nil
+}
+
+// releaseRateLimit returns the tokens reserved for a completion that failed to the RateLimiter
+func (c *LlmConfig) releaseRateLimit(reserved int) {
+	c.RateLimiter.Settle(reserved, 0)
+}
+
+// recordUsage reports the tokens used by a completion to OnUsage and the RateLimiter
+func (c *LlmConfig) recordUsage(reserved, inputTokens, outputTokens int) {
+	c.RateLimiter.Settle(reserved, inputTokens+outputTokens)
+	if c.OnUsage != nil {
+		c.OnUsage(c.ModelName, inputTokens, outputTokens)
+	}
 }
 
 // GenerateProjectDetails generates detailed project information based on a description
 func GenerateProjectDetails(client LlmClient, projectDesc string) (string, error) {
-	prompt := getProjectDetailsPrompt(projectDesc)
-	return client.GetCompletion(prompt, "text")
+	prompt, err := getProjectDetailsPrompt(projectDesc)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
 }
 
 // GenerateFileTree generates a file tree structure based on project details
 func GenerateFileTree(client LlmClient, projectDetails string) (string, error) {
-	prompt := getFileTreePrompt(projectDetails)
-	return client.GetCompletion(prompt, "text")
+	prompt, err := getFileTreePrompt(projectDetails)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
+}
+
+// GenerateAdditionDetails generates detailed information about a project to be added to an existing repository
+func GenerateAdditionDetails(client LlmClient, projectDesc, existingProject string) (string, error) {
+	prompt, err := getAdditionDetailsPrompt(projectDesc, existingProject)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
+}
+
+// GenerateAdditionFileTree generates the file tree of the new files to add to an existing repository
+func GenerateAdditionFileTree(client LlmClient, projectDetails, existingProject string) (string, error) {
+	prompt, err := getAdditionFileTreePrompt(projectDetails, existingProject)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
 }
 
 // DetermineFileOrder determines the order in which files should be created
 func DetermineFileOrder(client LlmClient, fileTree string) ([]string, error) {
-	prompt := getFileOrderPrompt(fileTree)
+	prompt, err := getFileOrderPrompt(fileTree)
+	if err != nil {
+		return nil, err
+	}
 	response, err := client.GetCompletion(prompt, "json_object")
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine file order: %w", err)
@@ -50,7 +127,10 @@ func DetermineFileOrder(client LlmClient, fileTree string) ([]string, error) {
 
 // GenerateFileOperations generates file operations for creating a specific file
 func GenerateFileOperations(client LlmClient, projectDetails, fileTree string) ([]fs.FileOperation, error) {
-	prompt := getFileOperationsPrompt(projectDetails, fileTree)
+	prompt, err := getFileOperationsPrompt(projectDetails, fileTree)
+	if err != nil {
+		return nil, err
+	}
 	response, err := client.GetCompletion(prompt, "json_object")
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate file operations: %w", err)
@@ -69,9 +149,58 @@ func GenerateFileOperations(client LlmClient, projectDetails, fileTree string) (
 	return operations["operations"], nil
 }
 
-// GenerateFileContent generates content for a specific file
-func GenerateFileContent(client LlmClient, fileName, projectDetails, fileTree string, previousFiles map[string]string) (string, error) {
-	prompt := getFileContentPrompt(fileName, projectDetails, fileTree, previousFiles)
+// GenerateFileContent generates content for a specific file, following the conventions of
+// the project's stack if any
+func GenerateFileContent(client LlmClient, fileName, projectDetails, fileTree string, previousFiles map[string]string, conventions string) (string, error) {
+	prompt, err := getFileContentPrompt(fileName, projectDetails, fileTree, previousFiles, conventions)
+	if err != nil {
+		return "", err
+	}
+	return generateFileContent(client, fileName, prompt)
+}
+
+// RegenerateFileContent generates new content for an existing file, following the given hint
+func RegenerateFileContent(client LlmClient, fileName, projectDetails, fileTree string, previousFiles map[string]string, conventions, currentContent, hint string) (string, error) {
+	prompt, err := getFileContentPrompt(fileName, projectDetails, fileTree, previousFiles, conventions)
+	if err != nil {
+		return "", err
+	}
+	instructions, err := getRegenerateFileHint(currentContent, hint)
+	if err != nil {
+		return "", err
+	}
+	return generateFileContent(client, fileName, prompt+"\n\n"+instructions)
+}
+
+// RepairFileContent regenerates the content of a file that fails to build, given the build
+// command and the errors it reported for the file
+func RepairFileContent(client LlmClient, fileName, projectDetails, fileTree string, previousFiles map[string]string, conventions, currentContent, command string, buildErrors []string) (string, error) {
+	prompt, err := getFileContentPrompt(fileName, projectDetails, fileTree, previousFiles, conventions)
+	if err != nil {
+		return "", err
+	}
+	instructions, err := getRepairFileHint(currentContent, command, strings.Join(buildErrors, "\n"))
+	if err != nil {
+		return "", err
+	}
+	return generateFileContent(client, fileName, prompt+"\n\n"+instructions)
+}
+
+// FixFileContent regenerates the content of a file that failed to parse, given the parse error
+func FixFileContent(client LlmClient, fileName, projectDetails, fileTree string, previousFiles map[string]string, conventions, currentContent, parseError string) (string, error) {
+	prompt, err := getFileContentPrompt(fileName, projectDetails, fileTree, previousFiles, conventions)
+	if err != nil {
+		return "", err
+	}
+	instructions, err := getInvalidFileHint(currentContent, parseError)
+	if err != nil {
+		return "", err
+	}
+	return generateFileContent(client, fileName, prompt+"\n\n"+instructions)
+}
+
+// generateFileContent requests the content of a file with the given prompt
+func generateFileContent(client LlmClient, fileName, prompt string) (string, error) {
 	var responseType string
 	if strings.HasSuffix(fileName, ".json") {
 		responseType = "json_object"
@@ -83,6 +212,9 @@ func GenerateFileContent(client LlmClient, fileName, projectDetails, fileTree st
 		return "", fmt.Errorf("failed to generate file content for %s: %w", fileName, err)
 	}
 
+	if responseType == "text" {
+		content = cleanFile(fileName, content)
+	}
 	if content == "" {
 		return "", fmt.Errorf("generated content for %s is empty", fileName)
 	}
@@ -90,20 +222,51 @@ func GenerateFileContent(client LlmClient, fileName, projectDetails, fileTree st
 	return content, nil
 }
 
+// completeText requests a text completion whose stray fences and preambles are removed
+func completeText(client LlmClient, prompt string) (string, error) {
+	content, err := client.GetCompletion(prompt, "text")
+	if err != nil {
+		return "", err
+	}
+	return CleanResponse(content), nil
+}
+
+// DescribeExistingProject generates project details for an existing project from a summary of it
+func DescribeExistingProject(client LlmClient, summary string) (string, error) {
+	prompt, err := getExistingProjectDetailsPrompt(summary)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
+}
+
 // GenerateReadmeContent generates content for a README file
 func GenerateReadmeContent(client LlmClient, projectDetails string) (string, error) {
-	prompt := getReadmePrompt(projectDetails)
-	return client.GetCompletion(prompt, "text")
+	prompt, err := getReadmePrompt(projectDetails)
+	if err != nil {
+		return "", err
+	}
+	content, err := client.GetCompletion(prompt, "text")
+	if err != nil {
+		return "", err
+	}
+	return cleanFile("README.md", content), nil
 }
 
 // GenerateGitignoreContent generates gitignore content
 func GenerateGitignoreContent(client LlmClient, projectDetails string) (string, error) {
-	prompt := getGitignorePrompt(projectDetails)
-	return client.GetCompletion(prompt, "text")
+	prompt, err := getGitignorePrompt(projectDetails)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
 }
 
 // GenerateDockerfileContent generates Dockerfile content
 func GenerateDockerfileContent(client LlmClient, projectDetails string) (string, error) {
-	prompt := getDockerfilePrompt(projectDetails)
-	return client.GetCompletion(prompt, "text")
+	prompt, err := getDockerfilePrompt(projectDetails)
+	if err != nil {
+		return "", err
+	}
+	return completeText(client, prompt)
 }
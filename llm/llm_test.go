@@ -153,7 +153,7 @@ func TestLlmSequential(t *testing.T) {
 		fileContent, err := cache.Get(cacheFileName)
 
 		if err != nil {
-			fileContent, err = GenerateFileContent(llmClient, fileName, projectDetails, fileTree, fileContentMap)
+			fileContent, err = GenerateFileContent(llmClient, fileName, projectDetails, fileTree, fileContentMap, "")
 			if err != nil {
 				t.Fatalf("FileContent error for %s: %v", fileName, err)
 			}
@@ -1,7 +1,6 @@
 package llm
 
 import (
-	"context"
 	"errors"
 	"fmt"
 
@@ -35,14 +34,22 @@ func NewOpenAIClient(cfg *LlmConfig, logger logger.Logger) (LlmClient, error) {
 
 // getCompletion sends a request to the OpenAI API and returns the generated text
 func (c *OpenAIClient) GetCompletion(prompt, responseType string) (string, error) {
+	system, err := getSystemPrompt(c.config.Conventions)
+	if err != nil {
+		return "", err
+	}
+	reserved, err := c.config.waitForRateLimit(system + prompt)
+	if err != nil {
+		return "", err
+	}
 	resp, err := c.openAIClient.CreateChatCompletion(
-		context.Background(),
+		c.config.context(),
 		openai.ChatCompletionRequest{
 			Model: c.config.ModelName,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
-					Content: getSystemPrompt(),
+					Content: system,
 				},
 				{
 					Role:    openai.ChatMessageRoleUser,
@@ -53,28 +60,46 @@ func (c *OpenAIClient) GetCompletion(prompt, responseType string) (string, error
 		},
 	)
 
+	if err != nil {
+		c.config.releaseRateLimit(reserved)
+	}
 	e := &openai.APIError{}
 	if errors.As(err, &e) {
+		apiErr := &APIError{StatusCode: e.HTTPStatusCode, Type: e.Type}
+		if code, ok := e.Code.(string); ok && code != "" {
+			apiErr.Type = code
+		}
 		switch e.HTTPStatusCode {
 		case 401:
 			// unauthorized
-			return "", fmt.Errorf("unauthorized: invalid OpenAI API key")
+			apiErr.Message = "unauthorized: invalid OpenAI API key"
 		case 429:
 			// rate limiting or engine overload (wait and retry)
-			return "", fmt.Errorf("rate limited by OpenAI API")
+			apiErr.Message = "rate limited by OpenAI API"
 		case 500:
 			// openai server error (retry)
-			return "", fmt.Errorf("OpenAI server error")
+			apiErr.Message = "OpenAI server error"
 		default:
 			// unhandled
-			return "", fmt.Errorf("OpenAI API error: %v", e)
+			apiErr.Message = fmt.Sprintf("OpenAI API error: %v", e)
 		}
+		return "", apiErr
+	}
+	re := &openai.RequestError{}
+	if errors.As(err, &re) {
+		// an error response that is not JSON, such as from a proxy
+		return "", &APIError{StatusCode: re.HTTPStatusCode, Message: fmt.Sprintf("error calling OpenAI API: %v", err)}
+	}
+	if err != nil {
+		return "", fmt.Errorf("error calling OpenAI API: %w", err)
 	}
 
+	usage := resp.Usage
+	c.config.recordUsage(reserved, usage.PromptTokens, usage.CompletionTokens)
+
 	if len(resp.Choices) == 0 {
 		return "", fmt.Errorf("no choices returned from OpenAI")
 	}
-	usage := resp.Usage
 	res := resp.Choices[0].Message.Content
 	err = c.tellmClient.Log(c.config.BatchID, prompt, res, c.config.ModelName, usage.PromptTokens, usage.CompletionTokens)
 	if err != nil {
@@ -1,335 +1,297 @@
 package llm
 
 import (
+	"bytes"
+	"embed"
 	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/spf13/afero"
 )
 
-func getSystemPrompt() string {
-	return `You are an expert software architect and developer. Your task is to generate detailed project specifications, file structures, and code content based on given requirements.
+// PromptExt is the extension of prompt template files
+const PromptExt = ".tmpl"
 
-Provide comprehensive, well-structured answers that directly address specific requirements. Ensure consistency across all generated content and follow best practices for the technologies involved.
+//go:embed prompts/*.tmpl
+var defaultPromptFiles embed.FS
 
-Tailor your responses to each prompt's context, considering previously generated information when applicable. Provide clear, actionable, and technically sound information to enable quick project implementation.
+// Data passed to each prompt template. Overrides may only use the fields of their prompt.
+type (
+	systemPromptData struct {
+		Conventions string
+	}
+	projectDetailsPromptData struct {
+		Description string
+	}
+	detailsPromptData struct {
+		ProjectDetails string
+	}
+	additionDetailsPromptData struct {
+		Description     string
+		ExistingProject string
+	}
+	additionFileTreePromptData struct {
+		ProjectDetails  string
+		ExistingProject string
+	}
+	fileOrderPromptData struct {
+		FileTree string
+	}
+	projectPromptData struct {
+		ProjectDetails string
+		FileTree       string
+	}
+	fileContentPromptData struct {
+		FilePath       string
+		ProjectDetails string
+		FileTree       string
+		PreviousFiles  map[string]string
+		Conventions    string
+	}
+	regenerateFilePromptData struct {
+		CurrentContent string
+		Hint           string
+	}
+	repairFilePromptData struct {
+		CurrentContent string
+		Command        string
+		BuildErrors    string
+	}
+	invalidFilePromptData struct {
+		CurrentContent string
+		ParseError     string
+	}
+	existingProjectPromptData struct {
+		Summary string
+	}
+)
 
-Do NOT use markdown code blocks at the beginning or end of your responses. Only use them in the middle when specifying code.`
+// promptSamples holds sample data for every prompt, used to validate templates when they
+// are loaded
+var promptSamples = map[string]interface{}{
+	"system":                   systemPromptData{Conventions: "conventions"},
+	"project_details":          projectDetailsPromptData{Description: "description"},
+	"file_tree":                detailsPromptData{ProjectDetails: "details"},
+	"addition_details":         additionDetailsPromptData{Description: "description", ExistingProject: "summary"},
+	"addition_file_tree":       additionFileTreePromptData{ProjectDetails: "details", ExistingProject: "summary"},
+	"file_order":               fileOrderPromptData{FileTree: "tree"},
+	"file_operations":          projectPromptData{ProjectDetails: "details", FileTree: "tree"},
+	"file_content":             fileContentPromptData{FilePath: "main.go", ProjectDetails: "details", FileTree: "tree", PreviousFiles: map[string]string{"go.mod": "module example"}, Conventions: "conventions"},
+	"regenerate_file":          regenerateFilePromptData{CurrentContent: "content", Hint: "hint"},
+	"repair_file":              repairFilePromptData{CurrentContent: "content", Command: "go build ./...", BuildErrors: "errors"},
+	"invalid_file":             invalidFilePromptData{CurrentContent: "content", ParseError: "error"},
+	"existing_project_details": existingProjectPromptData{Summary: "summary"},
+	"dockerfile":               detailsPromptData{ProjectDetails: "details"},
+	"readme":                   detailsPromptData{ProjectDetails: "details"},
+	"gitignore":                detailsPromptData{ProjectDetails: "details"},
 }
 
-func getProjectDetailsPrompt(projectDesc string) string {
-	return fmt.Sprintf(`Based on this project description: "%s"
-
-Generate a detailed project structure including:
-
-1. Main Components
-   - List the primary modules or components of the project
-   - Briefly describe the purpose and functionality of each component
-
-2. Dependencies and Frameworks
-   - Specify main dependencies and frameworks to be used
-   - Include version numbers where applicable
-   - Justify the choice of each major dependency or framework
-
-3. Configuration Requirements
-   - List necessary configuration files or environment variables
-   - Provide a brief description of each configuration's purpose
-
-4. Build System
-   - Recommend a build system or task runner if applicable
-   - Outline any build steps or scripts that will be needed
-
-5. Project Architecture
-   - Describe the overall architecture of the project
-   - Mention any specific architectural patterns or design principles to follow
-
-6. Additional Considerations
-   - Note any scalability, performance, or security considerations
-   - Suggest any best practices specific to this type of project
+var (
+	promptsMu sync.RWMutex
+	// prompts are the prompt templates in use, by name
+	prompts = mustParseDefaultPrompts()
+)
 
-Format your response as a structured markdown document with clear headings and subheadings.`, projectDesc)
+// mustParseDefaultPrompts parses the embedded prompt templates
+func mustParseDefaultPrompts() map[string]*template.Template {
+	defaults := DefaultPrompts()
+	parsed := make(map[string]*template.Template, len(defaults))
+	for name, text := range defaults {
+		tmpl, err := parsePrompt(name, text)
+		if err != nil {
+			panic(err)
+		}
+		parsed[name] = tmpl
+	}
+	return parsed
 }
 
-func getFileTreePrompt(projectDetails string) string {
-	return fmt.Sprintf(`Based on the following project details, generate a file tree structure for the project:
-
-%s
-
-Please provide a file tree structure that includes:
-1. Essential directories for the project structure
-2. Core source code files
-3. Configuration files
-4. Vital package files (e.g., package.json, go.mod, requirements.txt)
-
-Do NOT include:
-1. Package manager lock files (e.g., package-lock.json, yarn.lock, go.sum, Cargo.lock, etc.)
-2. Build output directories (e.g., /dist, /build)
-3. Dependency directories (e.g., /node_modules, /venv)
-4. IDE or editor-specific files or directories
-5. Temporary or cache files
-6. Any files that would be automatically generated during build or runtime
-7. Dockerfile
-8. README or other documentation files
-9. .gitignore
-
-Format the file tree as a text-based tree structure, using indentation to represent directory nesting. Always start with "project-root/" as the top-level directory.
-
-Here are examples for different programming languages:
-
-Python project:
-project-root/
-├── requirements.txt
-├── setup.py
-├── src/
-│   ├── __init__.py
-│   ├── main.py
-│   └── utils/
-│       ├── __init__.py
-│       └── helpers.py
-├── tests/
-│   ├── __init__.py
-│   └── test_main.py
-└── .env.example
-
-Go project:
-project-root/
-├── go.mod
-├── go.sum
-├── cmd/
-│   └── main.go
-├── internal/
-│   ├── app/
-│   │   └── app.go
-│   └── config/
-│       └── config.go
-├── pkg/
-│   └── utils/
-│       └── helpers.go
-└── test/
-    └── app_test.go
-
-Node.js project:
-project-root/
-├── package.json
-├── src/
-│   ├── index.js
-│   ├── config/
-│   │   └── config.js
-│   └── utils/
-│       └── helpers.js
-├── test/
-│   └── index.test.js
-└── .env.example
-
-Java project:
-project-root/
-├── pom.xml
-├── src/
-│   ├── main/
-│   │   ├── java/
-│   │   │   └── com/
-│   │   │       └── example/
-│   │   │           ├── App.java
-│   │   │           └── utils/
-│   │   │               └── Helpers.java
-│   │   └── resources/
-│   │       └── application.properties
-│   └── test/
-│       └── java/
-│           └── com/
-│               └── example/
-│                   └── AppTest.java
-
-Ruby project:
-project-root/
-├── Gemfile
-├── lib/
-│   ├── main.rb
-│   └── utils/
-│       └── helpers.rb
-├── spec/
-│   └── main_spec.rb
-└── config/
-    └── environment.rb
-
-Rust project:
-project-root/
-├── Cargo.toml
-├── src/
-│   ├── main.rs
-│   ├── lib.rs
-│   └── utils/
-│       └── mod.rs
-└── tests/
-    └── integration_test.rs
-
-Ensure the tree structure reflects a clean, production-ready project setup without including any auto-generated or temporary files. Remember to always use "project-root/" as the root directory name, regardless of the actual project name. Adjust the file tree based on the specific project details provided, using these examples as a guide for the appropriate structure for each language or framework.`, projectDetails)
+// DefaultPrompts returns the text of the default prompt templates, by name
+func DefaultPrompts() map[string]string {
+	defaults := make(map[string]string, len(promptSamples))
+	for name := range promptSamples {
+		content, err := defaultPromptFiles.ReadFile(path.Join("prompts", name+PromptExt))
+		if err != nil {
+			panic(fmt.Sprintf("missing default prompt %s: %v", name, err))
+		}
+		defaults[name] = string(content)
+	}
+	return defaults
 }
 
-func getFileOrderPrompt(fileTree string) string {
-	return fmt.Sprintf(`Given the following file tree structure:
-
-%s
-
-Provide an ordered list of files for which to generate content, considering the following criteria:
-1. Dependencies: Files that are required by other files should have their content generated first.
-2. Configuration: Config files should generally have their content generated early in the process.
-3. Core structure: Consider the logical structure of the project when ordering content generation.
-4. Logical progression: Follow a natural development flow (e.g., main application file after its imports).
-5. Testing: Test files typically should have their content generated after the files they are testing.
-
-Rules:
-- Include ALL files from the given file tree, even if they might be auto-generated later.
-- Use forward slashes (/) for path separators, regardless of the operating system.
-- Do not include empty directories.
-- File paths should be relative to the project root.
-- The order should reflect a practical sequence for generating content for the project from scratch.
-
-Return your response as a JSON object with a single key named "files", whose value is an array of file paths. Each file path should be a string. The JSON must be valid and directly parsable. Do not include any explanations, comments, or extra whitespace. For example:
-
-{
-"files":[".env","package.json","src/config/config.js","src/utils/helpers.js","src/index.js","tests/helpers.test.js","tests/index.test.js"]
+// PromptNames returns the names of the prompts in alphabetical order
+func PromptNames() []string {
+	names := make([]string, 0, len(promptSamples))
+	for name := range promptSamples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-The key MUST be named "files"`, fileTree)
+// DefaultPromptsDir returns the directory of the user's prompt overrides
+func DefaultPromptsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user home directory: %w", err)
+	}
+	return filepath.Join(home, ".boil", "prompts"), nil
 }
 
-func getFileContentPrompt(filePath, projectDetails, fileTree string, previousFiles map[string]string) string {
-	previousFilesContent := ""
-	for path, content := range previousFiles {
-		previousFilesContent += fmt.Sprintf("\n// file: %s\nContent:\n%s\n", path, content)
+// LoadPrompts overrides the default prompts with the <name>.tmpl templates in dir. Every
+// template is validated before any is used, so an invalid or unknown template leaves the
+// prompts unchanged. A missing directory is not an error.
+func LoadPrompts(fs afero.Fs, dir string) error {
+	entries, err := afero.ReadDir(fs, dir)
+	if os.IsNotExist(err) {
+		return nil
 	}
-
-	if previousFilesContent == "" {
-		previousFilesContent = "No previous files created."
+	if err != nil {
+		return fmt.Errorf("error reading prompts directory: %w", err)
 	}
 
-	return fmt.Sprintf(`Generate the content for the file "%s" based on the following project details, file tree, and previously created files:
-
-Project Details:
-%s
-
-File Tree:
-%s
-
-Previously Created Files:
-%s
-
-Please provide:
-1. The complete content of the file, including any necessary imports or requires
-2. Implement the necessary functionality based on the project details
-3. Follow best practices and conventions for the given tech stack
-4. Include brief comments explaining key parts of the code
-5. Ensure the code is complete and ready to use (no placeholders or TODOs)
-6. Make sure the content is consistent with and properly references the previously created files
+	overrides := make(map[string]*template.Template)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != PromptExt {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), PromptExt)
+		if _, ok := promptSamples[name]; !ok {
+			return fmt.Errorf("unknown prompt template %s (known prompts: %s)", filepath.Join(dir, entry.Name()), strings.Join(PromptNames(), ", "))
+		}
+		content, err := afero.ReadFile(fs, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("error reading prompt template %s: %w", entry.Name(), err)
+		}
+		tmpl, err := parsePrompt(name, string(content))
+		if err != nil {
+			return fmt.Errorf("invalid prompt template %s: %w", filepath.Join(dir, entry.Name()), err)
+		}
+		overrides[name] = tmpl
+	}
 
-Provide only the file content, without any markdown formatting or explanations outside of in-code comments.`, filePath, projectDetails, fileTree, previousFilesContent)
+	promptsMu.Lock()
+	defer promptsMu.Unlock()
+	for name, tmpl := range overrides {
+		prompts[name] = tmpl
+	}
+	return nil
 }
 
-func getFileOperationsPrompt(projectDetails, fileTree string) string {
-	return fmt.Sprintf(`Generate file operations for creating a project based on the following project details and file tree:
-
-Project Details:
-%s
-
-File Tree:
-%s
-
-Please provide:
-The file operation commands to create all files in the project, including both directories and files, EXCEPT for the project root directory.
-
-Format your response as a JSON object with a single key named "operations", whose value is an array of file operations. Each operation should have the following structure:
-{
-  "operations": [
-    {
-      "operation": "OPERATION_TYPE",
-      "path": "relative/path/to/file/or/directory"
-    },
-    ...
-  ]
+// resetPrompts restores the default prompts, discarding overrides
+func resetPrompts() {
+	promptsMu.Lock()
+	defer promptsMu.Unlock()
+	prompts = mustParseDefaultPrompts()
 }
 
-Valid operation types are: CREATE_DIR, CREATE_FILE
-
-Ensure that:
-1. All necessary parent directories are created before the file, except for the project root
-2. The operations follow the structure and conventions specified in the project details and file tree
-3. No actual file content is included in these operations
-4. Do NOT include a CREATE_DIR operation for the project root ("project-root/")
-
-The operations should only set up the file structure. File content will be generated separately.
-
-Ensure the JSON is valid and can be directly parsed. The key MUST be named "operations".`, projectDetails, fileTree)
+// parsePrompt parses a prompt template and checks that it renders with its prompt's data
+func parsePrompt(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, promptSamples[name]); err != nil {
+		return nil, fmt.Errorf("%w (available fields: %s)", err, strings.Join(promptFields(name), ", "))
+	}
+	return tmpl, nil
 }
 
-func getDockerfilePrompt(projectDetails string) string {
-	return fmt.Sprintf(`Based on the following project details, generate an appropriate Dockerfile:
-
-%s
-
-The Dockerfile should:
+// promptFields returns the fields a prompt template can use
+func promptFields(name string) []string {
+	t := reflect.TypeOf(promptSamples[name])
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, "."+t.Field(i).Name)
+	}
+	return fields
+}
 
-1. Use an official base image appropriate for the project's primary language/framework.
-2. Set up the working directory in the container.
-3. Copy the necessary project files into the container.
-4. Install any required dependencies.
-5. Expose any necessary ports.
-6. Specify the command to run the application.
+// renderPrompt renders a prompt template, without surrounding whitespace
+func renderPrompt(name string, data interface{}) (string, error) {
+	promptsMu.RLock()
+	tmpl, ok := prompts[name]
+	promptsMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown prompt %s", name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt %s: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
 
-Additionally, ensure that:
-1. The Dockerfile follows best practices for the chosen base image and project type.
-2. It includes comments explaining each significant step.
-3. It optimizes for build speed and image size where possible (e.g., using multi-stage builds if appropriate).
-4. Any environment-specific configurations are handled appropriately (e.g., using ARG or ENV instructions).
+func getSystemPrompt(conventions string) (string, error) {
+	return renderPrompt("system", systemPromptData{Conventions: conventions})
+}
 
-Return only the content of the Dockerfile, with no additional explanations or markdown formatting.`, projectDetails)
+func getProjectDetailsPrompt(projectDesc string) (string, error) {
+	return renderPrompt("project_details", projectDetailsPromptData{Description: projectDesc})
 }
 
-func getReadmePrompt(projectDetails string) string {
-	return fmt.Sprintf(`Based on the following project details, generate a concise README.md file suitable for an MVP (Minimum Viable Product):
+func getFileTreePrompt(projectDetails string) (string, error) {
+	return renderPrompt("file_tree", detailsPromptData{ProjectDetails: projectDetails})
+}
 
-%s
+func getAdditionDetailsPrompt(projectDesc, existingProject string) (string, error) {
+	return renderPrompt("addition_details", additionDetailsPromptData{Description: projectDesc, ExistingProject: existingProject})
+}
 
-The README should include the following sections:
+func getAdditionFileTreePrompt(projectDetails, existingProject string) (string, error) {
+	return renderPrompt("addition_file_tree", additionFileTreePromptData{ProjectDetails: projectDetails, ExistingProject: existingProject})
+}
 
-1. Project Title
-2. Brief Description
-3. Key Features (limit to 3-5 core features)
-4. Quick Start Guide
-  - Prerequisites (keep it minimal)
-  - Installation
-  - Basic Usage
-5. Configuration (if absolutely necessary, keep it brief)
-6. Basic Troubleshooting (optional, only if there are common issues)
+func getFileOrderPrompt(fileTree string) (string, error) {
+	return renderPrompt("file_order", fileOrderPromptData{FileTree: fileTree})
+}
 
-Format the README in Markdown. Ensure that:
-1. The content is clear, concise, and focuses on getting started quickly.
-2. Code snippets or commands are properly formatted in Markdown code blocks.
-3. Any placeholders for project-specific information are clearly marked (e.g., [YOUR_API_KEY]).
-4. The README provides just enough information for a developer to understand the project's purpose and get it running.
+func getFileContentPrompt(filePath, projectDetails, fileTree string, previousFiles map[string]string, conventions string) (string, error) {
+	return renderPrompt("file_content", fileContentPromptData{
+		FilePath:       filePath,
+		ProjectDetails: projectDetails,
+		FileTree:       fileTree,
+		PreviousFiles:  previousFiles,
+		Conventions:    conventions,
+	})
+}
 
-Omit sections on detailed API documentation, extensive testing procedures, deployment strategies, contributing guidelines, and licensing information.
+func getRegenerateFileHint(currentContent, hint string) (string, error) {
+	return renderPrompt("regenerate_file", regenerateFilePromptData{CurrentContent: currentContent, Hint: hint})
+}
 
-Return only the content of the README.md file, formatted in Markdown.`, projectDetails)
+func getRepairFileHint(currentContent, command, buildErrors string) (string, error) {
+	return renderPrompt("repair_file", repairFilePromptData{CurrentContent: currentContent, Command: command, BuildErrors: buildErrors})
 }
 
-func getGitignorePrompt(projectDetails string) string {
-	return fmt.Sprintf(`Based on the following project details, generate an appropriate .gitignore file:
+func getInvalidFileHint(currentContent, parseError string) (string, error) {
+	return renderPrompt("invalid_file", invalidFilePromptData{CurrentContent: currentContent, ParseError: parseError})
+}
 
-%s
+func getExistingProjectDetailsPrompt(summary string) (string, error) {
+	return renderPrompt("existing_project_details", existingProjectPromptData{Summary: summary})
+}
 
-The .gitignore file should:
+func getFileOperationsPrompt(projectDetails, fileTree string) (string, error) {
+	return renderPrompt("file_operations", projectPromptData{ProjectDetails: projectDetails, FileTree: fileTree})
+}
 
-1. Include common patterns for the primary programming language and framework used in the project.
-2. Exclude common operating system files and directories (e.g., .DS_Store for macOS, Thumbs.db for Windows).
-3. Ignore dependency directories (e.g., node_modules for Node.js projects).
-4. Exclude build output and compiled files.
-5. Ignore common IDE and text editor specific files and directories.
-6. Exclude log files and other runtime-generated files.
-7. Ignore environment-specific files (e.g., .env files containing sensitive information).
+func getDockerfilePrompt(projectDetails string) (string, error) {
+	return renderPrompt("dockerfile", detailsPromptData{ProjectDetails: projectDetails})
+}
 
-Additionally:
-- Group related ignore patterns together with clear comments.
-- Include patterns for both directories and files where applicable.
-- Use wildcard patterns judiciously to cover variations (e.g., *.log for all log files).
-- If the project uses a specific build tool or package manager, include relevant ignore patterns for those.
+func getReadmePrompt(projectDetails string) (string, error) {
+	return renderPrompt("readme", detailsPromptData{ProjectDetails: projectDetails})
+}
 
-Return only the content of the .gitignore file, with each pattern on a new line and using # for comments.`, projectDetails)
+func getGitignorePrompt(projectDetails string) (string, error) {
+	return renderPrompt("gitignore", detailsPromptData{ProjectDetails: projectDetails})
 }
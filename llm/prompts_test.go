@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestDefaultPrompts(t *testing.T) {
+	defaults := DefaultPrompts()
+	if len(defaults) != len(PromptNames()) {
+		t.Fatalf("expected %d default prompts, got %d", len(PromptNames()), len(defaults))
+	}
+	for name, text := range defaults {
+		if _, err := parsePrompt(name, text); err != nil {
+			t.Errorf("default prompt %s is invalid: %v", name, err)
+		}
+	}
+
+	prompt, err := getFileContentPrompt("main.go", "details", "tree", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "No previous files created.") || strings.Contains(prompt, "conventions for the project's language") {
+		t.Errorf("unexpected file content prompt:\n%s", prompt)
+	}
+}
+
+func TestLoadPrompts(t *testing.T) {
+	defer resetPrompts()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name:  "override",
+			files: map[string]string{"readme.tmpl": "Write a README for:\n{{.ProjectDetails}}\n", "notes.txt": "ignored"},
+		},
+		{
+			name:  "syntax error",
+			files: map[string]string{"readme.tmpl": "{{.ProjectDetails"},
+			err:   "invalid prompt template",
+		},
+		{
+			name:  "unknown field",
+			files: map[string]string{"readme.tmpl": "{{.FileTree}}"},
+			err:   "can't evaluate field FileTree",
+		},
+		{
+			name:  "unknown prompt",
+			files: map[string]string{"readmee.tmpl": "{{.ProjectDetails}}"},
+			err:   "unknown prompt template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetPrompts()
+			memFs := afero.NewMemMapFs()
+			for name, content := range tt.files {
+				if err := afero.WriteFile(memFs, "/prompts/"+name, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := LoadPrompts(memFs, "/prompts")
+			prompt, renderErr := getReadmePrompt("a CLI")
+			if renderErr != nil {
+				t.Fatal(renderErr)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected error containing %q, got %v", tt.err, err)
+				}
+				if !strings.HasPrefix(prompt, "Based on the following project details") {
+					t.Errorf("invalid templates must leave the defaults in place, got:\n%s", prompt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prompt != "Write a README for:\na CLI" {
+				t.Errorf("unexpected overridden prompt %q", prompt)
+			}
+		})
+	}
+
+	if err := LoadPrompts(afero.NewMemMapFs(), "/missing"); err != nil {
+		t.Errorf("a missing prompts directory must not be an error, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/santiagomed/boil/logger"
+)
+
+// Providers of LLM APIs
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// providerModels lists the models known to work with each provider, the default first
+var providerModels = map[string][]string{
+	ProviderOpenAI: {
+		"gpt-4o-mini",
+		"gpt-4o",
+		"gpt-4.1",
+		"gpt-4.1-mini",
+		"gpt-4.1-nano",
+		"gpt-4-turbo",
+		"gpt-4-turbo-preview",
+		"gpt-4",
+		"gpt-3.5-turbo",
+		"o1",
+		"o1-mini",
+		"o3-mini",
+	},
+	ProviderAnthropic: {
+		"claude-3-5-sonnet-latest",
+		"claude-3-5-haiku-latest",
+		"claude-3-7-sonnet-latest",
+		"claude-sonnet-4-20250514",
+		"claude-opus-4-20250514",
+		"claude-3-5-sonnet-20241022",
+		"claude-3-5-haiku-20241022",
+		"claude-3-opus-20240229",
+		"claude-3-haiku-20240307",
+	},
+}
+
+// Providers returns the supported providers in alphabetical order
+func Providers() []string {
+	providers := make([]string, 0, len(providerModels))
+	for provider := range providerModels {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// IsKnownProvider reports whether provider is supported
+func IsKnownProvider(provider string) bool {
+	_, ok := providerModels[provider]
+	return ok
+}
+
+// Models returns the models known to work with a provider, the default first
+func Models(provider string) []string {
+	return append([]string(nil), providerModels[provider]...)
+}
+
+// IsKnownModel reports whether model is known to work with provider
+func IsKnownModel(provider, model string) bool {
+	for _, m := range providerModels[provider] {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient creates a client for a provider
+func NewClient(provider string, cfg *LlmConfig, logger logger.Logger) (LlmClient, error) {
+	switch provider {
+	case ProviderOpenAI, "":
+		return NewOpenAIClient(cfg, logger)
+	case ProviderAnthropic:
+		return NewAnthropicClient(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown provider %q (supported providers: openai, anthropic)", provider)
+	}
+}
@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// RateLimiter delays completions to stay within a number of requests and tokens per minute.
+// Both limits are token buckets that hold up to a minute's worth and refill continuously. It
+// is safe for concurrent use, and completions wait in the order they asked.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests bucket
+	tokens   bucket
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// bucket is a token bucket that refills at rate per minute, up to rate. A limit of 0
+// disables it.
+type bucket struct {
+	rate      float64
+	available float64
+	last      time.Time
+}
+
+// refill adds what the bucket gained since it was last refilled
+func (b *bucket) refill(now time.Time) {
+	if b.rate <= 0 {
+		return
+	}
+	b.available += now.Sub(b.last).Minutes() * b.rate
+	if b.available > b.rate {
+		b.available = b.rate
+	}
+	b.last = now
+}
+
+// take removes n from the bucket and returns how long to wait until it is no longer in debt
+func (b *bucket) take(n float64) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.available -= n
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / b.rate * float64(time.Minute))
+}
+
+// give returns n to the bucket, up to its limit
+func (b *bucket) give(n float64) {
+	if b.rate <= 0 {
+		return
+	}
+	b.available += n
+	if b.available > b.rate {
+		b.available = b.rate
+	}
+}
+
+// setRate changes the limit of the bucket, starting full if it was disabled
+func (b *bucket) setRate(rate int, now time.Time) {
+	if b.rate <= 0 {
+		b.available = float64(rate)
+	}
+	b.rate = float64(rate)
+	b.last = now
+	if b.available > b.rate {
+		b.available = b.rate
+	}
+}
+
+// NewRateLimiter creates a limiter of requests and tokens per minute. A limit of 0 disables
+// it.
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	l := &RateLimiter{now: time.Now, sleep: sleep}
+	l.SetLimits(requestsPerMinute, tokensPerMinute)
+	return l
+}
+
+// SetLimits changes the limits of the limiter
+func (l *RateLimiter) SetLimits(requestsPerMinute, tokensPerMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.requests.refill(now)
+	l.tokens.refill(now)
+	l.requests.setRate(requestsPerMinute, now)
+	l.tokens.setRate(tokensPerMinute, now)
+}
+
+// Wait reserves a request and an estimate of its tokens, waits until the limits allow them
+// and returns how long it waited. If ctx is done first, the reservation is returned and
+// Wait returns the context's error.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	now := l.now()
+	l.requests.refill(now)
+	l.tokens.refill(now)
+	wait := l.requests.take(1)
+	// A completion larger than the limit waits for a full bucket rather than forever
+	reserve := float64(tokens)
+	if reserve < 0 {
+		reserve = 0
+	}
+	if l.tokens.rate > 0 && reserve > l.tokens.rate {
+		reserve = l.tokens.rate
+	}
+	if reserve > 0 {
+		if tokenWait := l.tokens.take(reserve); tokenWait > wait {
+			wait = tokenWait
+		}
+	}
+	l.mu.Unlock()
+
+	if wait > 0 {
+		if err := l.sleep(ctx, wait); err != nil {
+			l.mu.Lock()
+			l.requests.give(1)
+			l.tokens.give(reserve)
+			l.mu.Unlock()
+			return 0, err
+		}
+	}
+	return wait, nil
+}
+
+// Settle corrects the tokens reserved for a completion with the tokens it used. A completion
+// that failed is settled with no tokens used.
+func (l *RateLimiter) Settle(reserved, used int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens.refill(l.now())
+	l.tokens.take(float64(used - reserved))
+	if l.tokens.available > l.tokens.rate {
+		l.tokens.available = l.tokens.rate
+	}
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateLimiters holds a rate limiter per provider and API key, to share the limits of a key
+// between clients. It is safe for concurrent use.
+type RateLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+// NewRateLimiters creates an empty set of rate limiters
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{limiters: make(map[string]*RateLimiter)}
+}
+
+// Get returns the limiter of a provider's API key with the given limits, creating it if
+// needed, or nil if there is no limit
+func (r *RateLimiters) Get(provider, apiKey string, requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	if r == nil || (requestsPerMinute <= 0 && tokensPerMinute <= 0) {
+		return nil
+	}
+	// Key by a hash so that the map doesn't hold API keys
+	sum := sha256.Sum256([]byte(apiKey))
+	key := provider + ":" + hex.EncodeToString(sum[:8])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.limiters[key]
+	if !ok {
+		l = NewRateLimiter(requestsPerMinute, tokensPerMinute)
+		r.limiters[key] = l
+		return l
+	}
+	l.SetLimits(requestsPerMinute, tokensPerMinute)
+	return l
+}
+
+// estimateTokens roughly estimates the tokens of a prompt, at four characters per token
+func estimateTokens(prompt string) int {
+	return len(prompt)/4 + 1
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock whose sleeps advance it instantly
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func newTestLimiter(requestsPerMinute, tokensPerMinute int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := &RateLimiter{now: clock.Now, sleep: clock.Sleep}
+	l.SetLimits(requestsPerMinute, tokensPerMinute)
+	return l, clock
+}
+
+// wait waits for the limiter and returns how long it waited
+func wait(t *testing.T, l *RateLimiter, tokens int) time.Duration {
+	t.Helper()
+	d, err := l.Wait(context.Background(), tokens)
+	require.NoError(t, err)
+	return d
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("requests per minute", func(t *testing.T) {
+		l, clock := newTestLimiter(2, 0)
+		assert.Zero(t, wait(t, l, 100))
+		assert.Zero(t, wait(t, l, 100))
+		assert.Equal(t, 30*time.Second, wait(t, l, 100))
+		assert.Equal(t, 30*time.Second, wait(t, l, 100), "waits queue up")
+
+		require.NoError(t, clock.Sleep(context.Background(), 10*time.Minute))
+		assert.Zero(t, wait(t, l, 100))
+		assert.Zero(t, wait(t, l, 100), "the bucket holds at most a minute's worth")
+		assert.Equal(t, 30*time.Second, wait(t, l, 100))
+	})
+
+	t.Run("tokens per minute", func(t *testing.T) {
+		l, _ := newTestLimiter(0, 1000)
+		assert.Zero(t, wait(t, l, 800))
+		assert.Equal(t, 12*time.Second, wait(t, l, 400))
+
+		l, _ = newTestLimiter(0, 1000)
+		assert.Zero(t, wait(t, l, 100))
+		l.Settle(100, 1000)
+		assert.Equal(t, 6*time.Second, wait(t, l, 100), "used tokens beyond the estimate are charged")
+
+		l, _ = newTestLimiter(0, 1000)
+		assert.Equal(t, time.Duration(0), wait(t, l, 5000), "large completions wait for a full bucket")
+		assert.Equal(t, 6*time.Second, wait(t, l, 100))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		l, _ := newTestLimiter(1, 1000)
+		assert.Zero(t, wait(t, l, 1000))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := l.Wait(ctx, 1000)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, time.Minute, wait(t, l, 1000), "a cancelled wait returns its reservation")
+	})
+
+	t.Run("failed completion", func(t *testing.T) {
+		l, _ := newTestLimiter(0, 1000)
+		assert.Zero(t, wait(t, l, 800))
+		l.Settle(800, 0)
+		assert.Zero(t, wait(t, l, 800), "a failed completion uses no tokens")
+	})
+
+	t.Run("real sleep", func(t *testing.T) {
+		l := NewRateLimiter(1, 0)
+		assert.Zero(t, wait(t, l, 0))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := l.Wait(ctx, 0)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 10*time.Second, "a cancelled wait returns early")
+	})
+
+	var none *RateLimiter
+	assert.Zero(t, wait(t, none, 100))
+	none.Settle(100, 200)
+}
+
+func TestRateLimiters(t *testing.T) {
+	limiters := NewRateLimiters()
+	a := limiters.Get(ProviderOpenAI, "sk-a", 10, 0)
+	assert.NotNil(t, a)
+	assert.Same(t, a, limiters.Get(ProviderOpenAI, "sk-a", 20, 0))
+	assert.NotSame(t, a, limiters.Get(ProviderOpenAI, "sk-b", 10, 0))
+	assert.NotSame(t, a, limiters.Get(ProviderAnthropic, "sk-a", 10, 0))
+	assert.Nil(t, limiters.Get(ProviderOpenAI, "sk-a", 0, 0))
+
+	t.Run("cancelled", func(t *testing.T) {
+		l, _ := newTestLimiter(1, 1000)
+		assert.Zero(t, wait(t, l, 1000))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := l.Wait(ctx, 1000)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, time.Minute, wait(t, l, 1000), "a cancelled wait returns its reservation")
+	})
+
+	t.Run("failed completion", func(t *testing.T) {
+		l, _ := newTestLimiter(0, 1000)
+		assert.Zero(t, wait(t, l, 800))
+		l.Settle(800, 0)
+		assert.Zero(t, wait(t, l, 800), "a failed completion uses no tokens")
+	})
+
+	t.Run("real sleep", func(t *testing.T) {
+		l := NewRateLimiter(1, 0)
+		assert.Zero(t, wait(t, l, 0))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := l.Wait(ctx, 0)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 10*time.Second, "a cancelled wait returns early")
+	})
+
+	var none *RateLimiters
+	assert.Nil(t, none.Get(ProviderOpenAI, "sk-a", 10, 0))
+}
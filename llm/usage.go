@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Usage is the tokens used by the completions of one model for one kind of step
+type Usage struct {
+	// Kind is the kind of step the completions were made for, such as "planning" or "code"
+	Kind         string `json:"kind"`
+	Model        string `json:"model"`
+	Calls        int    `json:"calls"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	// Fallbacks counts the completions the model served after the preferred model failed
+	Fallbacks int `json:"fallbacks,omitempty"`
+	// Cost is the estimated cost in US dollars, or 0 if the model's price isn't known
+	Cost float64 `json:"cost_usd,omitempty"`
+}
+
+// UsageTracker adds up the tokens used by completions. It is safe for concurrent use.
+type UsageTracker struct {
+	mu    sync.Mutex
+	usage map[[2]string]*Usage
+}
+
+// NewUsageTracker creates an empty usage tracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{usage: make(map[[2]string]*Usage)}
+}
+
+// Recorder returns a function that records the usage of completions for a kind of step, to
+// be used as LlmConfig.OnUsage
+func (t *UsageTracker) Recorder(kind string) func(model string, inputTokens, outputTokens int) {
+	return func(model string, inputTokens, outputTokens int) {
+		t.Record(kind, model, inputTokens, outputTokens)
+	}
+}
+
+// Record adds the tokens used by a completion
+func (t *UsageTracker) Record(kind, model string, inputTokens, outputTokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u := t.entry(kind, model)
+	u.Calls++
+	u.InputTokens += inputTokens
+	u.OutputTokens += outputTokens
+}
+
+// RecordFallback counts a completion served by a model after the preferred model failed
+func (t *UsageTracker) RecordFallback(kind, model string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entry(kind, model).Fallbacks++
+}
+
+// entry returns the usage of a model for a kind of step, adding it if needed
+func (t *UsageTracker) entry(kind, model string) *Usage {
+	key := [2]string{kind, model}
+	u, ok := t.usage[key]
+	if !ok {
+		u = &Usage{Kind: kind, Model: model}
+		t.usage[key] = u
+	}
+	return u
+}
+
+// Usage returns the recorded usage sorted by kind and model, with estimated costs
+func (t *UsageTracker) Usage() []Usage {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage := make([]Usage, 0, len(t.usage))
+	for _, u := range t.usage {
+		cost, _ := EstimateCost(u.Model, u.InputTokens, u.OutputTokens)
+		entry := *u
+		entry.Cost = cost
+		usage = append(usage, entry)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Kind != usage[j].Kind {
+			return usage[i].Kind < usage[j].Kind
+		}
+		return usage[i].Model < usage[j].Model
+	})
+	return usage
+}
+
+// TotalUsage adds up usage entries. The total's Kind and Model are empty.
+func TotalUsage(usage []Usage) Usage {
+	var total Usage
+	for _, u := range usage {
+		total.Calls += u.Calls
+		total.InputTokens += u.InputTokens
+		total.OutputTokens += u.OutputTokens
+		total.Fallbacks += u.Fallbacks
+		total.Cost += u.Cost
+	}
+	return total
+}
+
+// modelPrice is the price of a model in US dollars per million tokens
+type modelPrice struct {
+	input  float64
+	output float64
+}
+
+// modelPrices are the prices of known models by name prefix. Longer prefixes are matched
+// first, so "gpt-4o-mini" isn't priced as "gpt-4o".
+var modelPrices = map[string]modelPrice{
+	"gpt-4o-mini":       {0.15, 0.60},
+	"gpt-4o":            {2.50, 10},
+	"gpt-4.1-nano":      {0.10, 0.40},
+	"gpt-4.1-mini":      {0.40, 1.60},
+	"gpt-4.1":           {2, 8},
+	"gpt-4-turbo":       {10, 30},
+	"gpt-4":             {30, 60},
+	"gpt-3.5-turbo":     {0.50, 1.50},
+	"o1-mini":           {1.10, 4.40},
+	"o1":                {15, 60},
+	"o3-mini":           {1.10, 4.40},
+	"claude-3-5-sonnet": {3, 15},
+	"claude-3-7-sonnet": {3, 15},
+	"claude-sonnet-4":   {3, 15},
+	"claude-opus-4":     {15, 75},
+	"claude-3-opus":     {15, 75},
+	"claude-3-5-haiku":  {0.80, 4},
+	"claude-3-haiku":    {0.25, 1.25},
+}
+
+// EstimateCost returns the cost in US dollars of the tokens used with a model, and whether
+// the model's price is known
+func EstimateCost(model string, inputTokens, outputTokens int) (float64, bool) {
+	var match string
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return 0, false
+	}
+	price := modelPrices[match]
+	return (float64(inputTokens)*price.input + float64(outputTokens)*price.output) / 1e6, true
+}
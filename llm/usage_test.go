@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker(t *testing.T) {
+	tracker := NewUsageTracker()
+	planning := tracker.Recorder("planning")
+	code := tracker.Recorder("code")
+	planning("gpt-4o-mini", 1000, 200)
+	planning("gpt-4o-mini", 500, 100)
+	code("gpt-4o", 2000, 1000)
+	code("my-model", 10, 10)
+
+	usage := tracker.Usage()
+	require.Len(t, usage, 3)
+	assert.Equal(t, Usage{Kind: "code", Model: "gpt-4o", Calls: 1, InputTokens: 2000, OutputTokens: 1000, Cost: 0.015}, usage[0])
+	assert.Equal(t, "my-model", usage[1].Model)
+	assert.Zero(t, usage[1].Cost)
+	assert.Equal(t, "planning", usage[2].Kind)
+	assert.Equal(t, 2, usage[2].Calls)
+	assert.Equal(t, 1500, usage[2].InputTokens)
+
+	total := TotalUsage(usage)
+	assert.Equal(t, 4, total.Calls)
+	assert.Equal(t, 3510, total.InputTokens)
+	assert.Equal(t, 1310, total.OutputTokens)
+
+	var none *UsageTracker
+	assert.Nil(t, none.Usage())
+}
+
+func TestEstimateCost(t *testing.T) {
+	cost, ok := EstimateCost("gpt-4o-mini", 1000000, 1000000)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.75, cost, 1e-9, "gpt-4o-mini is not priced as gpt-4o")
+
+	cost, ok = EstimateCost("claude-3-5-haiku-20241022", 1000000, 0)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.80, cost, 1e-9)
+
+	_, ok = EstimateCost("llama-3", 100, 100)
+	assert.False(t, ok)
+}
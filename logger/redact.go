@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redacted replaces secrets in redacted text
+const Redacted = "[REDACTED]"
+
+// Redact replaces the secrets in s with Redacted. Empty secrets are ignored.
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+// redactingLogger removes secrets from the messages and fields of a logger
+type redactingLogger struct {
+	logger  Logger
+	secrets []string
+}
+
+// NewRedactingLogger wraps a logger so that secrets, such as API keys, never reach it
+func NewRedactingLogger(l Logger, secrets ...string) Logger {
+	var nonEmpty []string
+	for _, secret := range secrets {
+		if secret != "" {
+			nonEmpty = append(nonEmpty, secret)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return l
+	}
+	return &redactingLogger{logger: l, secrets: nonEmpty}
+}
+
+func (r *redactingLogger) Debug(msg string) { r.logger.Debug(Redact(msg, r.secrets...)) }
+func (r *redactingLogger) Info(msg string)  { r.logger.Info(Redact(msg, r.secrets...)) }
+func (r *redactingLogger) Warn(msg string)  { r.logger.Warn(Redact(msg, r.secrets...)) }
+func (r *redactingLogger) Error(msg string) { r.logger.Error(Redact(msg, r.secrets...)) }
+func (r *redactingLogger) Fatal(msg string) { r.logger.Fatal(Redact(msg, r.secrets...)) }
+func (r *redactingLogger) WithField(key string, value interface{}) Logger {
+	if s := fmt.Sprint(value); Redact(s, r.secrets...) != s {
+		value = Redact(s, r.secrets...)
+	}
+	return &redactingLogger{logger: r.logger.WithField(key, value), secrets: r.secrets}
+}
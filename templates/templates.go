@@ -0,0 +1,200 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/santiagomed/boil/core"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// Components are the optional components of a template. Templates that set them skip the
+// questions about optional components.
+type Components struct {
+	GitRepo    bool `yaml:"git_repo"`
+	GitIgnore  bool `yaml:"git_ignore"`
+	Readme     bool `yaml:"readme"`
+	Dockerfile bool `yaml:"dockerfile"`
+}
+
+// Template is a named preset of a project request
+type Template struct {
+	Name string `yaml:"-"`
+	// Description is the project description, asked for interactively if empty
+	Description  string      `yaml:"description,omitempty"`
+	Components   *Components `yaml:"components,omitempty"`
+	ModelName    string      `yaml:"model_name,omitempty"`
+	Instructions string      `yaml:"instructions,omitempty"`
+	// FileTree pins the file tree of generated projects instead of generating it
+	FileTree string `yaml:"file_tree,omitempty"`
+}
+
+// Apply merges the template into a request, overriding the request's values with the ones
+// the template sets
+func (t *Template) Apply(r *core.Request) {
+	if t.Description != "" {
+		r.ProjectDescription = t.Description
+	}
+	if t.Components != nil {
+		r.GitRepo = t.Components.GitRepo
+		r.GitIgnore = t.Components.GitIgnore
+		r.Readme = t.Components.Readme
+		r.Dockerfile = t.Components.Dockerfile
+	}
+	if t.ModelName != "" {
+		r.ModelName = t.ModelName
+	}
+	if t.Instructions != "" {
+		r.Instructions = t.Instructions
+	}
+	if t.FileTree != "" {
+		r.FileTree = t.FileTree
+	}
+}
+
+// namePattern matches valid template names, which are used as file names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+var (
+	// ErrNotFound is returned when a template does not exist
+	ErrNotFound = errors.New("template not found")
+	// ErrExists is returned when saving a template over an existing one
+	ErrExists = errors.New("template already exists")
+)
+
+// ValidateName checks that a template name can be used as a file name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid template name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+	return nil
+}
+
+// Store keeps templates as <name>.yaml files in a directory
+type Store struct {
+	fs  afero.Fs
+	dir string
+}
+
+// NewStore creates a store for the templates in dir
+func NewStore(fs afero.Fs, dir string) *Store {
+	return &Store{fs: fs, dir: dir}
+}
+
+// DefaultDir returns the directory of the user's templates
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user home directory: %w", err)
+	}
+	return filepath.Join(home, ".boil", "templates"), nil
+}
+
+// path returns the file of a template
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".yaml")
+}
+
+// List returns the names of the stored templates in alphabetical order
+func (s *Store) List() ([]string, error) {
+	entries, err := afero.ReadDir(s.fs, s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing templates: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || name == entry.Name() || ValidateName(name) != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads a template, returning ErrNotFound if it does not exist
+func (s *Store) Load(name string) (*Template, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	content, err := afero.ReadFile(s.fs, s.path(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading template %s: %w", name, err)
+	}
+	var t Template
+	if err := yaml.Unmarshal(content, &t); err != nil {
+		return nil, fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+	t.Name = name
+	return &t, nil
+}
+
+// Save writes a template. An existing template with the same name is only replaced if
+// overwrite is set.
+func (s *Store) Save(t *Template, overwrite bool) error {
+	if err := ValidateName(t.Name); err != nil {
+		return err
+	}
+	exists, err := afero.Exists(s.fs, s.path(t.Name))
+	if err != nil {
+		return fmt.Errorf("error accessing template %s: %w", t.Name, err)
+	}
+	if exists && !overwrite {
+		return fmt.Errorf("%w: %s", ErrExists, t.Name)
+	}
+
+	content, err := Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := s.fs.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("error creating templates directory: %w", err)
+	}
+	if err := afero.WriteFile(s.fs, s.path(t.Name), content, 0644); err != nil {
+		return fmt.Errorf("error writing template %s: %w", t.Name, err)
+	}
+	return nil
+}
+
+// Delete removes a template, returning ErrNotFound if it does not exist
+func (s *Store) Delete(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	err := s.fs.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting template %s: %w", name, err)
+	}
+	return nil
+}
+
+// Marshal encodes a template as YAML
+func Marshal(t *Template) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(t); err != nil {
+		return nil, fmt.Errorf("error encoding template %s: %w", t.Name, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding template %s: %w", t.Name, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/santiagomed/boil/core"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	memFs := afero.NewMemMapFs()
+	store := NewStore(memFs, "/home/user/.boil/templates")
+
+	names, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	service := &Template{
+		Name:         "go-grpc-service",
+		Description:  "A gRPC service in Go",
+		Components:   &Components{GitRepo: true, Readme: true},
+		ModelName:    "gpt-4o",
+		Instructions: "Use buf to generate the protobuf code",
+		FileTree:     "service/\n├── go.mod\n└── main.go\n",
+	}
+	require.NoError(t, store.Save(service, false))
+	require.NoError(t, store.Save(&Template{Name: "cli"}, false))
+	require.NoError(t, afero.WriteFile(memFs, "/home/user/.boil/templates/notes.txt", []byte("notes"), 0644))
+
+	names, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cli", "go-grpc-service"}, names)
+
+	loaded, err := store.Load("go-grpc-service")
+	require.NoError(t, err)
+	assert.Equal(t, service, loaded)
+
+	err = store.Save(&Template{Name: "cli", Description: "A CLI"}, false)
+	assert.True(t, errors.Is(err, ErrExists))
+	require.NoError(t, store.Save(&Template{Name: "cli", Description: "A CLI"}, true))
+	loaded, err = store.Load("cli")
+	require.NoError(t, err)
+	assert.Equal(t, "A CLI", loaded.Description)
+	assert.Nil(t, loaded.Components)
+
+	require.NoError(t, store.Delete("cli"))
+	_, err = store.Load("cli")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(store.Delete("cli"), ErrNotFound))
+
+	assert.Error(t, store.Save(&Template{Name: "../config"}, false))
+	_, err = store.Load("")
+	assert.Error(t, err)
+}
+
+func TestTemplate_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		template Template
+		request  core.Request
+		expected core.Request
+	}{
+		{
+			name:     "empty template keeps the request",
+			template: Template{Name: "empty"},
+			request:  core.Request{ProjectDescription: "A web app", ModelName: "gpt-4o-mini", Readme: true},
+			expected: core.Request{ProjectDescription: "A web app", ModelName: "gpt-4o-mini", Readme: true},
+		},
+		{
+			name: "template overrides the request",
+			template: Template{
+				Name:         "service",
+				Description:  "A gRPC service",
+				Components:   &Components{GitRepo: true, Dockerfile: true},
+				ModelName:    "gpt-4o",
+				Instructions: "Use buf",
+				FileTree:     "service/\n",
+			},
+			request: core.Request{ProjectDescription: "A web app", ModelName: "gpt-4o-mini", Readme: true, ProjectName: "billing"},
+			expected: core.Request{
+				ProjectDescription: "A gRPC service",
+				ProjectName:        "billing",
+				ModelName:          "gpt-4o",
+				GitRepo:            true,
+				Dockerfile:         true,
+				Instructions:       "Use buf",
+				FileTree:           "service/\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request
+			tt.template.Apply(&request)
+			assert.Equal(t, tt.expected, request)
+		})
+	}
+}
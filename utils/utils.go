@@ -104,7 +104,9 @@ func Unzip(zipFileName string, destination string) error {
 		filePath := filepath.Join(destination, file.Name)
 
 		if file.FileInfo().IsDir() {
-			os.MkdirAll(filePath, os.ModePerm)
+			if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -112,22 +114,37 @@ func Unzip(zipFileName string, destination string) error {
 			return err
 		}
 
-		dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
+		if err := extractZipFile(file, filePath); err != nil {
 			return err
 		}
-		defer dstFile.Close()
+	}
 
-		srcFile, err := file.Open()
-		if err != nil {
-			return err
-		}
-		defer srcFile.Close()
+	return nil
+}
 
-		if _, err := io.Copy(dstFile, srcFile); err != nil {
-			return err
-		}
+// extractZipFile writes a single zip entry to disk, preserving its file mode
+func extractZipFile(file *zip.File, filePath string) error {
+	mode := file.Mode().Perm()
+	if mode == 0 {
+		mode = 0644
 	}
 
-	return nil
+	dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	srcFile, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		return err
+	}
+
+	// The mode passed to OpenFile is subject to umask and ignored for existing files
+	return os.Chmod(filePath, mode)
 }
@@ -0,0 +1,59 @@
+package verify
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxErrorLines is the number of error lines kept for each file
+const maxErrorLines = 50
+
+// AffectedFiles maps the lines of build output to the project files they mention, so that
+// each file can be repaired with its own errors. files are the slash-separated paths of the
+// project files; paths in the output may be relative, prefixed with "./", or absolute
+// paths inside the verification directory. Lines that mention no file are dropped.
+func AffectedFiles(output string, files []string) map[string][]string {
+	// Match longer paths first, so "cmd/main.go" wins over "main.go"
+	sorted := append([]string(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	affected := make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		for _, file := range sorted {
+			if !mentions(line, file) {
+				continue
+			}
+			if len(affected[file]) < maxErrorLines {
+				affected[file] = append(affected[file], strings.TrimSpace(line))
+			}
+			break
+		}
+	}
+	return affected
+}
+
+// mentions reports whether line refers to file as a whole path, followed by a position
+// such as ":12:3" or "(12,3)", quoted as in Python tracebacks, or at the end of the line
+func mentions(line, file string) bool {
+	for start := 0; ; {
+		i := strings.Index(line[start:], file)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(file)
+		before := byte(' ')
+		if i > 0 {
+			before = line[i-1]
+		}
+		after := byte(':')
+		if end < len(line) {
+			after = line[end]
+		}
+		if strings.IndexByte(" \t/\"'(", before) >= 0 && strings.IndexByte(":(\"',", after) >= 0 {
+			return true
+		}
+		start = i + 1
+	}
+}
@@ -0,0 +1,493 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santiagomed/boil/fs"
+	"github.com/spf13/afero"
+)
+
+// Issue is a problem found by a static check of a generated project
+type Issue struct {
+	// File is the slash-separated path of the file the issue is in
+	File string `json:"file"`
+	// Line is the line of the issue, or 0 if it concerns the whole file
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.File, i.Message)
+}
+
+// goModule is a Go module of a generated project
+type goModule struct {
+	// dir is the slash-separated directory containing the go.mod file, "." for the root
+	dir  string
+	path string
+}
+
+// goPackage is a directory of Go files of a generated project
+type goPackage struct {
+	dir        string
+	importPath string
+	files      []*ast.File
+	// names maps each parsed file of the package to its path
+	names map[*ast.File]string
+	// broken is set if a file of the package has syntax errors, so it is not type-checked
+	broken bool
+}
+
+// goChecker checks the Go files of a generated project
+type goChecker struct {
+	fset     *token.FileSet
+	modules  []goModule
+	packages map[string]*goPackage
+	// byImportPath maps import paths to packages, for packages inside a module
+	byImportPath map[string]*goPackage
+	checked      map[string]*types.Package
+	checking     map[string]bool
+	issues       []Issue
+}
+
+// CheckGo statically checks the Go files of a project without a Go toolchain. It reports
+// syntax errors, package clauses that differ between files of a directory or from the
+// directory name, go.mod files without a module path, imports of packages that the project
+// should contain but does not, and imports that only match a project package under a
+// different module path. Generated packages are also type-checked against each other;
+// packages outside the project are not available, so uses of them are not checked.
+// Issues are sorted by file and line; a project without Go files has none.
+func CheckGo(project *fs.FileSystem) ([]Issue, error) {
+	c := &goChecker{
+		fset:         token.NewFileSet(),
+		packages:     make(map[string]*goPackage),
+		byImportPath: make(map[string]*goPackage),
+		checked:      make(map[string]*types.Package),
+		checking:     make(map[string]bool),
+	}
+	if err := c.load(project); err != nil {
+		return nil, err
+	}
+	if len(c.packages) == 0 {
+		return nil, nil
+	}
+
+	c.checkModules()
+	dirs := make([]string, 0, len(c.packages))
+	for dir, pkg := range c.packages {
+		if len(pkg.files) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		pkg := c.packages[dir]
+		c.checkPackageClauses(pkg)
+		c.checkImports(pkg)
+	}
+	for _, dir := range dirs {
+		if pkg := c.packages[dir]; !pkg.broken {
+			c.typeCheck(pkg)
+		}
+	}
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].File != c.issues[j].File {
+			return c.issues[i].File < c.issues[j].File
+		}
+		return c.issues[i].Line < c.issues[j].Line
+	})
+	return c.issues, nil
+}
+
+// load parses the go.mod and Go files of the project
+func (c *goChecker) load(project *fs.FileSystem) error {
+	err := afero.Walk(project.Fs, ".", func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(p)
+		if info.IsDir() {
+			if name := info.Name(); p != "." && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case info.Name() == "go.mod":
+			content, err := afero.ReadFile(project.Fs, p)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", p, err)
+			}
+			module := goModule{dir: path.Dir(slashPath), path: modulePath(content)}
+			if module.path == "" {
+				c.issues = append(c.issues, Issue{File: slashPath, Message: "go.mod has no module path"})
+			}
+			c.modules = append(c.modules, module)
+		case strings.HasSuffix(info.Name(), ".go") && !strings.HasSuffix(info.Name(), "_test.go"):
+			content, err := afero.ReadFile(project.Fs, p)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", p, err)
+			}
+			c.parse(slashPath, content)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error loading Go files: %w", err)
+	}
+
+	// Modules are matched innermost first
+	sort.Slice(c.modules, func(i, j int) bool { return len(c.modules[i].dir) > len(c.modules[j].dir) })
+	for _, pkg := range c.packages {
+		module, ok := c.moduleOf(pkg.dir)
+		if !ok || module.path == "" {
+			continue
+		}
+		pkg.importPath = module.path
+		if rel := relDir(module.dir, pkg.dir); rel != "" {
+			pkg.importPath += "/" + rel
+		}
+		c.byImportPath[pkg.importPath] = pkg
+	}
+	return nil
+}
+
+// parse parses a Go file into the package of its directory, recording its first syntax
+// error. Files without a package clause are left out of their package.
+func (c *goChecker) parse(file string, content []byte) {
+	f, err := parser.ParseFile(c.fset, file, content, 0)
+	var list scanner.ErrorList
+	dir := path.Dir(file)
+	pkg, ok := c.packages[dir]
+	if !ok {
+		pkg = &goPackage{dir: dir, names: make(map[*ast.File]string)}
+		c.packages[dir] = pkg
+	}
+	if err != nil {
+		pkg.broken = true
+	}
+	if errors.As(err, &list) && len(list) > 0 {
+		// Later errors are mostly consequences of the first one
+		c.issues = append(c.issues, Issue{File: file, Line: list[0].Pos.Line, Message: list[0].Msg})
+	} else if err != nil {
+		c.issues = append(c.issues, Issue{File: file, Message: err.Error()})
+	}
+	if f == nil || f.Name == nil || f.Name.Name == "_" || f.Name.Name == "" {
+		return
+	}
+	pkg.files = append(pkg.files, f)
+	pkg.names[f] = file
+}
+
+// moduleOf returns the innermost module containing dir
+func (c *goChecker) moduleOf(dir string) (goModule, bool) {
+	for _, module := range c.modules {
+		if module.dir == "." || dir == module.dir || strings.HasPrefix(dir, module.dir+"/") {
+			return module, true
+		}
+	}
+	return goModule{}, false
+}
+
+// checkModules reports Go packages outside of any module
+func (c *goChecker) checkModules() {
+	for _, pkg := range c.packages {
+		if _, ok := c.moduleOf(pkg.dir); !ok && len(pkg.files) > 0 {
+			c.issues = append(c.issues, Issue{
+				File:    pkg.names[pkg.files[0]],
+				Message: "no go.mod found for this package",
+			})
+		}
+	}
+}
+
+// checkPackageClauses reports files whose package clause differs from the other files of
+// their directory, or packages not named after their directory
+func (c *goChecker) checkPackageClauses(pkg *goPackage) {
+	want := packageName(pkg)
+	for _, f := range pkg.files {
+		if name := f.Name.Name; name != want {
+			c.issues = append(c.issues, Issue{
+				File:    pkg.names[f],
+				Line:    c.fset.Position(f.Name.Pos()).Line,
+				Message: fmt.Sprintf("package %s does not match package %s of the other files in %s", name, want, pkg.dir),
+			})
+		}
+	}
+
+	module, _ := c.moduleOf(pkg.dir)
+	expected := dirPackageName(pkg.dir)
+	if want == "main" || pkg.dir == module.dir || expected == "" || normalizeName(want) == expected {
+		return
+	}
+	for _, f := range pkg.files {
+		if f.Name.Name == want {
+			c.issues = append(c.issues, Issue{
+				File:    pkg.names[f],
+				Line:    c.fset.Position(f.Name.Pos()).Line,
+				Message: fmt.Sprintf("package %s does not match its directory %s (expected package %s)", want, pkg.dir, expected),
+			})
+			return
+		}
+	}
+}
+
+// packageName returns the package name used by most files of a package, preferring the
+// directory name and then the alphabetically first name on ties
+func packageName(pkg *goPackage) string {
+	counts := make(map[string]int)
+	for _, f := range pkg.files {
+		counts[f.Name.Name]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	expected := dirPackageName(pkg.dir)
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		if (normalizeName(names[i]) == expected) != (normalizeName(names[j]) == expected) {
+			return normalizeName(names[i]) == expected
+		}
+		return names[i] < names[j]
+	})
+	return names[0]
+}
+
+// checkImports reports imports of missing project packages, and imports that match a
+// project package under a different module path
+func (c *goChecker) checkImports(pkg *goPackage) {
+	for _, f := range pkg.files {
+		for _, spec := range f.Imports {
+			importPath := strings.Trim(spec.Path.Value, "`\"")
+			line := c.fset.Position(spec.Pos()).Line
+			file := pkg.names[f]
+
+			if _, ok := c.byImportPath[importPath]; ok {
+				continue
+			}
+			if module, ok := c.moduleFor(importPath); ok {
+				c.issues = append(c.issues, Issue{
+					File:    file,
+					Line:    line,
+					Message: fmt.Sprintf("import %q is in module %s, but the project has no package %s", importPath, module.path, path.Join(module.dir, strings.TrimPrefix(importPath, module.path))),
+				})
+				continue
+			}
+			if match := c.suffixMatch(importPath); match != nil {
+				c.issues = append(c.issues, Issue{
+					File:    file,
+					Line:    line,
+					Message: fmt.Sprintf("import %q does not match the module path of the project package %s (expected %q)", importPath, match.dir, match.importPath),
+				})
+			}
+		}
+	}
+}
+
+// moduleFor returns the project module whose path prefixes importPath
+func (c *goChecker) moduleFor(importPath string) (goModule, bool) {
+	var best goModule
+	for _, module := range c.modules {
+		if module.path == "" {
+			continue
+		}
+		if (importPath == module.path || strings.HasPrefix(importPath, module.path+"/")) && len(module.path) > len(best.path) {
+			best = module
+		}
+	}
+	return best, best.path != ""
+}
+
+// suffixMatch returns the project package whose directory ends importPath, which usually
+// means the import was written with a different module path than the one in go.mod
+func (c *goChecker) suffixMatch(importPath string) *goPackage {
+	var best *goPackage
+	for _, pkg := range c.packages {
+		if pkg.importPath == "" || pkg.dir == "." {
+			continue
+		}
+		module, _ := c.moduleOf(pkg.dir)
+		rel := relDir(module.dir, pkg.dir)
+		if rel != "" && strings.HasSuffix(importPath, "/"+rel) && (best == nil || len(pkg.dir) > len(best.dir)) {
+			best = pkg
+		}
+	}
+	return best
+}
+
+// typeCheck type-checks a project package, and the project packages it imports first
+func (c *goChecker) typeCheck(pkg *goPackage) (*types.Package, error) {
+	if checked, ok := c.checked[pkg.dir]; ok {
+		return checked, nil
+	}
+	if c.checking[pkg.dir] {
+		return nil, fmt.Errorf("import cycle through %s", pkg.importPath)
+	}
+	c.checking[pkg.dir] = true
+	defer delete(c.checking, pkg.dir)
+
+	// Files with a mismatched package clause are already reported
+	name := packageName(pkg)
+	var files []*ast.File
+	for _, f := range pkg.files {
+		if f.Name.Name == name {
+			files = append(files, f)
+		}
+	}
+
+	// Packages outside the project, and project packages with syntax errors, are stubbed,
+	// so errors about their contents are dropped. Their names are guessed, so they are not
+	// reported as unused either.
+	stubs := make(map[string]bool)
+	for _, f := range files {
+		for _, spec := range f.Imports {
+			dep, ok := c.byImportPath[strings.Trim(spec.Path.Value, "`\"")]
+			if spec.Name != nil && (!ok || dep.broken) {
+				stubs[spec.Name.Name] = true
+			}
+		}
+	}
+	conf := types.Config{
+		Importer: importerFunc(func(importPath string) (*types.Package, error) {
+			if dep, ok := c.byImportPath[importPath]; ok && !dep.broken {
+				return c.typeCheck(dep)
+			}
+			stub := types.NewPackage(importPath, guessPackageName(importPath))
+			stub.MarkComplete()
+			stubs[stub.Name()] = true
+			stubs[strconv.Quote(importPath)] = true
+			return stub, nil
+		}),
+		Error: func(err error) {
+			typeErr, ok := err.(types.Error)
+			if !ok || isStubError(typeErr.Msg, stubs) {
+				return
+			}
+			position := c.fset.Position(typeErr.Pos)
+			c.issues = append(c.issues, Issue{File: position.Filename, Line: position.Line, Message: typeErr.Msg})
+		},
+	}
+
+	importPath := pkg.importPath
+	if importPath == "" {
+		importPath = pkg.dir
+	}
+	checked, _ := conf.Check(importPath, c.fset, files, nil)
+	c.checked[pkg.dir] = checked
+	return checked, nil
+}
+
+// stubErrorPattern matches errors about identifiers looked up in a package
+var stubErrorPattern = regexp.MustCompile(`^undefined: (\w+)\.`)
+
+// unusedImportPattern matches errors about unused imports
+var unusedImportPattern = regexp.MustCompile(`^("[^"]+") imported (?:as \w+ )?and not used`)
+
+// isStubError reports whether a type error is about the contents or use of a stubbed package.
+// stubs holds the names and quoted import paths of the stubbed packages.
+func isStubError(msg string, stubs map[string]bool) bool {
+	if m := stubErrorPattern.FindStringSubmatch(msg); m != nil {
+		return stubs[m[1]]
+	}
+	if m := unusedImportPattern.FindStringSubmatch(msg); m != nil {
+		return stubs[m[1]]
+	}
+	return false
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// versionSuffix matches major version path elements such as "v2"
+var versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// guessPackageName guesses the name of a package from its import path, following common
+// conventions: "github.com/go-chi/chi/v5" is chi, "gopkg.in/yaml.v3" is yaml and
+// "github.com/mattn/go-sqlite3" is sqlite3
+func guessPackageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if versionSuffix.MatchString(name) && len(elems) > 1 {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "-go")
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// dirPackageName returns the package name conventionally used for a directory, normalized
+// with normalizeName, or "" if there is no convention
+func dirPackageName(dir string) string {
+	if dir == "." {
+		return ""
+	}
+	name := path.Base(dir)
+	if versionSuffix.MatchString(name) {
+		return ""
+	}
+	return normalizeName(name)
+}
+
+// normalizeName lowercases a package or directory name and removes separators, so that
+// "user_service" and "user-service" both match "userservice"
+func normalizeName(name string) string {
+	return strings.NewReplacer("-", "", ".", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// relDir returns the slash-separated directory dir relative to the module directory
+// moduleDir, or "" if they are the same
+func relDir(moduleDir, dir string) string {
+	if dir == moduleDir {
+		return ""
+	}
+	if moduleDir == "." {
+		return dir
+	}
+	return strings.TrimPrefix(dir, moduleDir+"/")
+}
+
+// modulePath returns the module path declared in a go.mod file
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package verify
+
+import "os/exec"
+
+// setProcessGroup does nothing on Windows, where only the command itself is stopped
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup stops the command
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
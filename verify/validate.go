@@ -0,0 +1,164 @@
+package verify
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Validator checks the syntax of the content of a file
+type Validator func(content string) error
+
+// Validators is a registry of validators by file extension
+type Validators struct {
+	byExt map[string]Validator
+}
+
+// NewValidators creates a registry with validators for JSON, YAML, TOML, XML and .env files
+func NewValidators() *Validators {
+	v := &Validators{byExt: make(map[string]Validator)}
+	v.Register(".json", ValidateJSON)
+	v.Register(".yaml", ValidateYAML)
+	v.Register(".yml", ValidateYAML)
+	v.Register(".toml", ValidateTOML)
+	v.Register(".xml", ValidateXML)
+	v.Register(".env", ValidateEnv)
+	return v
+}
+
+// Register sets the validator of files with the extension ext, such as ".json"
+func (v *Validators) Register(ext string, validator Validator) {
+	v.byExt[strings.ToLower(ext)] = validator
+}
+
+// For returns the validator of a file. Files named after an extension, such as ".env",
+// use the validator of that extension. It is safe to call on a nil registry.
+func (v *Validators) For(file string) (Validator, bool) {
+	if v == nil {
+		return nil, false
+	}
+	ext := strings.ToLower(path.Ext(file))
+	if ext == "" {
+		return nil, false
+	}
+	validator, ok := v.byExt[ext]
+	return validator, ok
+}
+
+// ValidateJSON checks that content is a single JSON value
+func ValidateJSON(content string) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+// ValidateYAML checks that content is a stream of valid YAML documents
+func ValidateYAML(content string) error {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+}
+
+// ValidateTOML checks that content is a valid TOML document
+func ValidateTOML(content string) error {
+	var value map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &value); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, column := decodeErr.Position()
+			return fmt.Errorf("invalid TOML at line %d, column %d: %w", row, column, err)
+		}
+		return fmt.Errorf("invalid TOML: %w", err)
+	}
+	return nil
+}
+
+// ValidateXML checks that content is well-formed XML with a root element
+func ValidateXML(content string) error {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = true
+	root := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid XML: %w", err)
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			root = true
+		}
+	}
+	if !root {
+		return errors.New("invalid XML: no root element")
+	}
+	return nil
+}
+
+// envKeyPattern matches variable names in .env files, optionally exported
+var envKeyPattern = regexp.MustCompile(`^(?:export\s+)?[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ValidateEnv checks that content is a .env file of KEY=VALUE lines, comments and blank
+// lines. Quoted values must be closed; double-quoted values may span lines.
+func ValidateEnv(content string) error {
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(strings.TrimRight(lines[i], "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || !envKeyPattern.MatchString(strings.TrimSpace(key)) {
+			return fmt.Errorf("invalid .env file at line %d: expected KEY=VALUE", i+1)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			continue
+		}
+		quote := value[0]
+		start := i
+		rest := value[1:]
+		for !closesQuote(rest, quote) {
+			if quote == '\'' || i+1 >= len(lines) {
+				return fmt.Errorf("invalid .env file at line %d: unterminated quoted value", start+1)
+			}
+			i++
+			rest = strings.TrimRight(lines[i], "\r")
+		}
+	}
+	return nil
+}
+
+// closesQuote reports whether s contains an unescaped closing quote
+func closesQuote(s string, quote byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/santiagomed/boil/fs"
+	"github.com/spf13/afero"
+)
+
+// Stack is a technology stack detected from a project's manifest files
+type Stack string
+
+const (
+	StackGo     Stack = "go"
+	StackNode   Stack = "node"
+	StackRust   Stack = "rust"
+	StackPython Stack = "python"
+)
+
+// DefaultTimeout is how long a verification command may run by default
+const DefaultTimeout = 5 * time.Minute
+
+// maxOutputLength is the number of bytes of command output kept in results
+const maxOutputLength = 64 * 1024
+
+// stacks maps manifest files to their stack and default verification command, in detection order
+var stacks = []struct {
+	manifest string
+	stack    Stack
+	command  string
+}{
+	{"go.mod", StackGo, "go build ./..."},
+	{"Cargo.toml", StackRust, "cargo build"},
+	{"package.json", StackNode, "npm install && npm test"},
+	{"pyproject.toml", StackPython, "python -m compileall -q ."},
+}
+
+// Result is the outcome of verifying a project
+type Result struct {
+	Stack    Stack         `json:"stack,omitempty"`
+	Command  string        `json:"command"`
+	Passed   bool          `json:"passed"`
+	ExitCode int           `json:"exit_code"`
+	Output   string        `json:"output"`
+	Duration time.Duration `json:"duration"`
+}
+
+// DetectStack detects the stack of a project from the manifest files in its root directory
+func DetectStack(project *fs.FileSystem) (Stack, bool) {
+	for _, s := range stacks {
+		if project.Exists(s.manifest) {
+			return s.stack, true
+		}
+	}
+	return "", false
+}
+
+// DefaultCommand returns the command used to verify projects of a stack
+func DefaultCommand(stack Stack) string {
+	for _, s := range stacks {
+		if s.stack == stack {
+			return s.command
+		}
+	}
+	return ""
+}
+
+// Run copies the project to a temporary directory and runs command in it through the
+// shell. A command that runs but exits with an error is reported as a failed result,
+// not as an error.
+func Run(ctx context.Context, project *fs.FileSystem, command string, timeout time.Duration) (*Result, error) {
+	if command == "" {
+		return nil, errors.New("no verification command")
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dir, err := os.MkdirTemp("", "boil-verify-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating verification directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := project.CopyDir(afero.NewOsFs(), ".", dir); err != nil {
+		return nil, fmt.Errorf("error copying project for verification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := shellCommand(command)
+	cmd.Dir = dir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	setProcessGroup(cmd)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error running %q: %w", command, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		err = <-done
+	}
+	result := &Result{
+		Command:  command,
+		Duration: time.Since(start),
+		Output:   truncate(output.String()),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.ExitCode = -1
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Output += fmt.Sprintf("\ncommand timed out after %v", timeout)
+		} else {
+			result.Output += "\ncommand cancelled"
+		}
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("error running %q: %w", command, err)
+	default:
+		result.Passed = true
+	}
+	return result, nil
+}
+
+// shellCommand runs command through the platform shell
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// truncate keeps the end of long command output, where errors usually are
+func truncate(output string) string {
+	if len(output) <= maxOutputLength {
+		return output
+	}
+	return "... (truncated)\n" + output[len(output)-maxOutputLength:]
+}
@@ -0,0 +1,269 @@
+package verify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/santiagomed/boil/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectStack(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected Stack
+		detected bool
+	}{
+		{"go", []string{"go.mod", "main.go"}, StackGo, true},
+		{"node", []string{"package.json", "index.js"}, StackNode, true},
+		{"rust", []string{"Cargo.toml", "src/main.rs"}, StackRust, true},
+		{"python", []string{"pyproject.toml", "app.py"}, StackPython, true},
+		{"nested manifest", []string{"web/package.json"}, "", false},
+		{"unknown", []string{"README.md"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := fs.NewMemoryFileSystem()
+			for _, file := range tt.files {
+				assert.NoError(t, project.WriteFile(file, "content"))
+			}
+			stack, detected := DetectStack(project)
+			assert.Equal(t, tt.expected, stack)
+			assert.Equal(t, tt.detected, detected)
+		})
+	}
+
+	assert.Equal(t, "go build ./...", DefaultCommand(StackGo))
+	assert.Equal(t, "", DefaultCommand("unknown"))
+}
+
+func TestRun(t *testing.T) {
+	project := fs.NewMemoryFileSystem()
+	assert.NoError(t, project.WriteFile("src/main.txt", "hello"))
+
+	result, err := Run(context.Background(), project, "cat src/main.txt", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, result.Passed)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "hello", result.Output)
+	assert.Equal(t, "cat src/main.txt", result.Command)
+
+	result, err = Run(context.Background(), project, "echo broken >&2; exit 3", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "broken\n", result.Output)
+
+	result, err = Run(context.Background(), project, "sleep 5", 100*time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.Contains(t, result.Output, "timed out")
+
+	_, err = Run(context.Background(), project, "", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestAffectedFiles(t *testing.T) {
+	files := []string{"main.go", "cmd/server/main.go", "src/index.ts", "app/models.py", "src/main.rs"}
+
+	tests := []struct {
+		name     string
+		output   string
+		expected map[string][]string
+	}{
+		{
+			name:   "go",
+			output: "# example/cmd/server\n./cmd/server/main.go:12:2: undefined: handler\nmain.go:3:8: \"fmt\" imported and not used\n",
+			expected: map[string][]string{
+				"cmd/server/main.go": {"./cmd/server/main.go:12:2: undefined: handler"},
+				"main.go":            {"main.go:3:8: \"fmt\" imported and not used"},
+			},
+		},
+		{
+			name:   "typescript",
+			output: "src/index.ts(4,7): error TS2322: Type 'string' is not assignable to type 'number'.",
+			expected: map[string][]string{
+				"src/index.ts": {"src/index.ts(4,7): error TS2322: Type 'string' is not assignable to type 'number'."},
+			},
+		},
+		{
+			name:   "rust",
+			output: "error[E0425]: cannot find value `x` in this scope\n  --> src/main.rs:2:5\n",
+			expected: map[string][]string{
+				"src/main.rs": {"--> src/main.rs:2:5"},
+			},
+		},
+		{
+			name:   "python absolute path",
+			output: "  File \"/tmp/boil-verify-123/app/models.py\", line 3\n    def broken(\n",
+			expected: map[string][]string{
+				"app/models.py": {"File \"/tmp/boil-verify-123/app/models.py\", line 3"},
+			},
+		},
+		{
+			name:     "partial file name",
+			output:   "notmain.go:1:1: expected package\nsrc/main.rsx:1:1: error",
+			expected: map[string][]string{},
+		},
+		{
+			name:     "no files",
+			output:   "npm ERR! missing script: test",
+			expected: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, AffectedFiles(tt.output, files))
+		})
+	}
+}
+
+func TestCheckGo(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+	}{
+		{
+			name: "valid project",
+			files: map[string]string{
+				"go.mod":                       "module github.com/acme/api\n\ngo 1.21\n",
+				"main.go":                      "package main\n\nimport (\n\t\"fmt\"\n\t\"net/http\"\n\n\t\"github.com/acme/api/internal/store\"\n\tchi \"github.com/go-chi/chi/v5\"\n)\n\nfunc main() {\n\tr := chi.NewRouter()\n\tfmt.Println(store.New().Name)\n\thttp.ListenAndServe(\":8080\", r)\n}\n",
+				"internal/store/store.go":      "package store\n\nimport \"gopkg.in/yaml.v3\"\n\ntype Store struct{ Name string }\n\nfunc New() *Store {\n\tyaml.Marshal(nil)\n\treturn &Store{}\n}\n",
+				"internal/store/store_test.go": "package store_test\n\nthis is not go",
+			},
+		},
+		{
+			name:  "no go files",
+			files: map[string]string{"package.json": "{}"},
+		},
+		{
+			name: "syntax error",
+			files: map[string]string{
+				"go.mod":  "module example\n",
+				"main.go": "package main\n\nfunc main() {\n\tfmt.Println(\"hi\"\n}\n",
+			},
+			expected: []string{"main.go:4: missing ',' before newline in argument list"},
+		},
+		{
+			name: "package clauses",
+			files: map[string]string{
+				"go.mod":            "module example\n",
+				"main.go":           "package main\n",
+				"handlers/a.go":     "package handlers\n",
+				"handlers/b.go":     "package handler\n",
+				"user-service/u.go": "package userservice\n",
+				"models/m.go":       "package entities\n",
+			},
+			expected: []string{
+				"handlers/b.go:1: package handler does not match package handlers of the other files in handlers",
+				"models/m.go:1: package entities does not match its directory models (expected package models)",
+			},
+		},
+		{
+			name: "imports between generated packages",
+			files: map[string]string{
+				"go.mod":     "module github.com/acme/api\n",
+				"main.go":    "package main\n\nimport (\n\t\"github.com/acme/api/db\"\n\t\"github.com/acme/api/config\"\n)\n\nfunc main() {\n\tdb.Connect()\n\tconfig.Load()\n}\n",
+				"db/db.go":   "package db\n\nfunc Open() {}\n",
+				"cache/c.go": "package cache\n\nimport \"api/db\"\n\nvar _ = db.Open\n",
+			},
+			expected: []string{
+				"cache/c.go:3: import \"api/db\" does not match the module path of the project package db (expected \"github.com/acme/api/db\")",
+				"main.go:5: import \"github.com/acme/api/config\" is in module github.com/acme/api, but the project has no package config",
+				"main.go:9: undefined: db.Connect",
+			},
+		},
+		{
+			name: "type errors",
+			files: map[string]string{
+				"go.mod":  "module example\n",
+				"main.go": "package main\n\nimport \"os\"\n\nfunc main() {\n\tx := 1\n\tos.Exit(undefinedValue)\n}\n",
+			},
+			expected: []string{
+				"main.go:6: declared and not used: x",
+				"main.go:7: undefined: undefinedValue",
+			},
+		},
+		{
+			name: "missing go.mod",
+			files: map[string]string{
+				"main.go": "package main\n\nfunc main() {}\n",
+			},
+			expected: []string{"main.go: no go.mod found for this package"},
+		},
+		{
+			name: "go.mod without module",
+			files: map[string]string{
+				"go.mod":  "go 1.21\n",
+				"main.go": "package main\n\nfunc main() {}\n",
+			},
+			expected: []string{"go.mod: go.mod has no module path"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := fs.NewMemoryFileSystem()
+			for path, content := range tt.files {
+				assert.NoError(t, project.WriteFile(path, content))
+			}
+			issues, err := CheckGo(project)
+			assert.NoError(t, err)
+			var actual []string
+			for _, issue := range issues {
+				actual = append(actual, issue.String())
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestValidators(t *testing.T) {
+	validators := NewValidators()
+
+	tests := []struct {
+		file    string
+		content string
+		valid   bool
+	}{
+		{"package.json", `{"name": "app", "version": "1.0.0"}`, true},
+		{"package.json", `{"name": "app",}`, false},
+		{"config/app.yaml", "server:\n  port: 8080\n---\nother: doc\n", true},
+		{"docker-compose.yml", "services:\n  web:\n    image: nginx\n   ports: [80]\n", false},
+		{"Cargo.toml", "[package]\nname = \"app\"\nversion = \"0.1.0\"\n", true},
+		{"pyproject.toml", "[project]\nname = app\n", false},
+		{"pom.xml", "<?xml version=\"1.0\"?>\n<project><modelVersion>4.0.0</modelVersion></project>\n", true},
+		{"pom.xml", "<project><modelVersion>4.0.0</project>", false},
+		{"pom.xml", "<?xml version=\"1.0\"?>\n", false},
+		{".env", "# Settings\nPORT=8080\nexport DEBUG=true\nKEY=\"multi\nline\"\nEMPTY=\n", true},
+		{".env", "PORT 8080\n", false},
+		{"prod.env", "SECRET='unterminated\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			validator, ok := validators.For(tt.file)
+			assert.True(t, ok)
+			err := validator(tt.content)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	for _, file := range []string{"main.go", "Dockerfile", ".env.example", "README.md"} {
+		_, ok := validators.For(file)
+		assert.False(t, ok, file)
+	}
+
+	var nilValidators *Validators
+	_, ok := nilValidators.For("package.json")
+	assert.False(t, ok)
+}